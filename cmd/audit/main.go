@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/service"
+	"github.com/iagocanalejas/rstats/internal/types"
+	"github.com/iagocanalejas/rstats/internal/utils/arrays"
+	"github.com/iagocanalejas/rstats/internal/utils/assert"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// OUTPUT FORMATS
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+	FORMAT_CSV  = "csv"
+)
+
+func main() {
+	available := []string{
+		types.CHECK_LAPS_ORDER, types.CHECK_LAPS_COUNT, types.CHECK_LANE, types.CHECK_SERIES_GAP,
+		types.CHECK_NULL_DISTANCE, types.CHECK_DUPLICATE_CLUB, types.CHECK_CLUB_NAME, types.CHECK_EMPTY_RACE,
+	}
+
+	pflag.StringSliceVarP(&checks, "checks", "c", available, fmt.Sprintf("checks to report. Available checks: %s", strings.Join(available, ", ")))
+	pflag.StringVarP(&severity, "severity", "s", types.SEVERITY_INFO, "minimum severity to report: info, warning or error")
+	pflag.StringVarP(&format, "format", "f", FORMAT_TEXT, "output format. Available formats: text, json, csv")
+	pflag.StringVarP(&output, "output", "o", "", "file to write the findings to, stdout when not given")
+	pflag.BoolVarP(&verbose, "verbose", "v", false, "define log level to DEBUG")
+	dbFlags := db.RegisterFlags(pflag.CommandLine)
+	pflag.Parse()
+
+	for _, check := range checks {
+		assert.Contains(check, available, "invalid check=%s", check)
+	}
+	assert.Contains(severity, []string{types.SEVERITY_INFO, types.SEVERITY_WARNING, types.SEVERITY_ERROR}, "invalid severity=%s", severity)
+	assert.Contains(format, []string{FORMAT_TEXT, FORMAT_JSON, FORMAT_CSV}, "invalid format=%s", format)
+
+	if verbose {
+		prettylog.SetLevel(prettylog.DEBUG)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dbConfig, err := dbFlags.Config()
+	assert.NoError(err, "loading database config: %v", err)
+
+	s, err := service.Init(ctx, dbConfig)
+	assert.NoError(err, "initializing service: %v", err)
+
+	findings, err := s.Audit(ctx)
+	assert.NoError(err, "auditing data: %v", err)
+
+	filtered := make([]types.Finding, 0, len(findings))
+	counts := make(map[string]int)
+	for _, finding := range findings {
+		if arrays.Contains(checks, finding.Check) && types.SeverityLevel(finding.Severity) >= types.SeverityLevel(severity) {
+			filtered = append(filtered, finding)
+			counts[finding.Severity]++
+		}
+	}
+
+	w := os.Stdout
+	if output != "" {
+		w, err = os.Create(output)
+		assert.NoError(err, "creating output file: %v", err)
+		defer w.Close()
+	}
+
+	err = writeFindings(w, filtered)
+	assert.NoError(err, "writing findings: %v", err)
+
+	prettylog.Info("%d errors, %d warnings, %d infos", counts[types.SEVERITY_ERROR], counts[types.SEVERITY_WARNING], counts[types.SEVERITY_INFO])
+	if counts[types.SEVERITY_ERROR] > 0 {
+		// deferred calls don't run on os.Exit
+		w.Close()
+		os.Exit(1)
+	}
+}
+
+func writeFindings(w io.Writer, findings []types.Finding) error {
+	switch format {
+	case FORMAT_JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(findings)
+	case FORMAT_CSV:
+		writer := csv.NewWriter(w)
+		_ = writer.Write([]string{"check", "severity", "race_id", "participant_id", "club_id", "message"})
+		for _, finding := range findings {
+			_ = writer.Write([]string{
+				finding.Check,
+				finding.Severity,
+				strconv.FormatInt(finding.RaceID, 10),
+				strconv.FormatInt(finding.ParticipantID, 10),
+				strconv.FormatInt(finding.ClubID, 10),
+				finding.Message,
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Severity\tCheck\tRace\tParticipant\tClub\tMessage")
+	for _, finding := range findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			finding.Severity, finding.Check, formatID(finding.RaceID), formatID(finding.ParticipantID), formatID(finding.ClubID), finding.Message)
+	}
+	return tw.Flush()
+}
+
+func formatID(id int64) string {
+	if id == 0 {
+		return "-"
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+var (
+	checks   []string
+	severity string
+	format   string
+	output   string
+
+	verbose bool
+)
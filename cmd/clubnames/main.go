@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/service"
+	"github.com/iagocanalejas/rstats/internal/types"
+	"github.com/iagocanalejas/rstats/internal/utils/assert"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"github.com/lib/pq"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// REPORT FORMATS
+	FORMAT_JSON     = "json"
+	FORMAT_MARKDOWN = "markdown"
+)
+
+func main() {
+	pflag.StringVarP(&clubName, "club", "c", "", "club ID or name to limit the report to")
+	pflag.Float64Var(&minScore, "min-score", service.MIN_SUGGESTION_SCORE, "name score needed to suggest moving a raw name to another club")
+	pflag.BoolVar(&clusters, "clusters", false, "also report the raw names of each club")
+	pflag.StringVarP(&format, "format", "f", FORMAT_MARKDOWN, "report format. Available formats: json, markdown")
+	pflag.StringVarP(&output, "output", "o", "", "file to write the report to, stdout when not given")
+	pflag.StringVar(&patchFile, "sql", "", "file to write the SQL patch applying the suggestions to")
+	pflag.BoolVarP(&verbose, "verbose", "v", false, "define log level to DEBUG")
+	dbFlags := db.RegisterFlags(pflag.CommandLine)
+	pflag.Parse()
+
+	assert.Contains(format, []string{FORMAT_JSON, FORMAT_MARKDOWN}, "invalid format=%s", format)
+	assert.Assert(minScore > 0 && minScore <= 1, "invalid min-score=%f", minScore)
+
+	if verbose {
+		prettylog.SetLevel(prettylog.DEBUG)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dbConfig, err := dbFlags.Config()
+	assert.NoError(err, "loading database config: %v", err)
+
+	s, err := service.Init(ctx, dbConfig)
+	assert.NoError(err, "initializing service: %v", err)
+
+	review, err := s.ReviewClubNames(ctx, minScore)
+	assert.NoError(err, "reviewing club names: %v", err)
+
+	if clubName != "" {
+		club, err := s.ResolveClub(ctx, clubName)
+		assert.NoError(err, "invalid club=%s: %v", clubName, err)
+		prettylog.Info("club=%s resolved to %d (%s)", clubName, club.ID, club.Name)
+		review = filterReview(review, club.ID)
+	}
+	if !clusters {
+		review.Clubs = nil
+	}
+	prettylog.Info("found %d conflicts and %d suggestions", len(review.Conflicts), len(review.Suggestions))
+
+	w := os.Stdout
+	if output != "" {
+		w, err = os.Create(output)
+		assert.NoError(err, "creating report file=%s: %v", output, err)
+		defer w.Close()
+	}
+
+	switch format {
+	case FORMAT_JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		err = encoder.Encode(review)
+	case FORMAT_MARKDOWN:
+		err = writeMarkdown(w, review)
+	}
+	assert.NoError(err, "writing report: %v", err)
+
+	if patchFile != "" {
+		patch, err := os.Create(patchFile)
+		assert.NoError(err, "creating patch file=%s: %v", patchFile, err)
+		defer patch.Close()
+
+		err = writePatch(patch, review.Suggestions)
+		assert.NoError(err, "writing patch: %v", err)
+		prettylog.Info("written %d updates to %s", len(review.Suggestions), patchFile)
+	}
+}
+
+// filterReview keeps the parts of the review involving the club.
+func filterReview(review *types.ClubNamesReview, clubID int64) *types.ClubNamesReview {
+	filtered := &types.ClubNamesReview{
+		Clubs:       make([]types.ClubRawNames, 0),
+		Conflicts:   make([]types.RawNameConflict, 0),
+		Suggestions: make([]types.MappingSuggestion, 0),
+	}
+	for _, club := range review.Clubs {
+		if club.Club.ID == clubID {
+			filtered.Clubs = append(filtered.Clubs, club)
+		}
+	}
+	for _, conflict := range review.Conflicts {
+		for _, usage := range conflict.Clubs {
+			if usage.Club.ID == clubID {
+				filtered.Conflicts = append(filtered.Conflicts, conflict)
+				break
+			}
+		}
+	}
+	for _, suggestion := range review.Suggestions {
+		if suggestion.From.ID == clubID || suggestion.To.ID == clubID {
+			filtered.Suggestions = append(filtered.Suggestions, suggestion)
+		}
+	}
+	return filtered
+}
+
+func writeMarkdown(w io.Writer, review *types.ClubNamesReview) error {
+	lines := []string{"## Suggestions", ""}
+	lines = append(lines, markdownTable([]string{"Raw name", "From", "To", "Participants", "Score", "Current score", "Reason"}, len(review.Suggestions), func(idx int) []string {
+		suggestion := review.Suggestions[idx]
+		return []string{
+			suggestion.RawName,
+			formatClub(suggestion.From),
+			formatClub(suggestion.To),
+			strconv.Itoa(suggestion.Participants),
+			fmt.Sprintf("%.2f", suggestion.Score),
+			fmt.Sprintf("%.2f", suggestion.CurrentScore),
+			suggestion.Reason,
+		}
+	})...)
+
+	lines = append(lines, "", "## Conflicts", "")
+	lines = append(lines, markdownTable([]string{"Raw name", "Clubs"}, len(review.Conflicts), func(idx int) []string {
+		conflict := review.Conflicts[idx]
+		clubs := make([]string, len(conflict.Clubs))
+		for i, usage := range conflict.Clubs {
+			clubs[i] = fmt.Sprintf("%s: %d participants in %d races", formatClub(usage.Club), usage.Participants, usage.Races)
+		}
+		return []string{conflict.RawName, strings.Join(clubs, ", ")}
+	})...)
+
+	if len(review.Clubs) > 0 {
+		lines = append(lines, "", "## Clubs", "")
+		lines = append(lines, markdownTable([]string{"Club", "Raw names"}, len(review.Clubs), func(idx int) []string {
+			club := review.Clubs[idx]
+			rawNames := make([]string, len(club.RawNames))
+			for i, usage := range club.RawNames {
+				rawNames[i] = fmt.Sprintf("%s (%d)", usage.RawName, usage.Participants)
+			}
+			return []string{formatClub(club.Club), strings.Join(rawNames, ", ")}
+		})...)
+	}
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func markdownTable(header []string, rows int, row func(idx int) []string) []string {
+	separators := make([]string, len(header))
+	for idx := range separators {
+		separators[idx] = "---"
+	}
+
+	lines := []string{markdownRow(header), markdownRow(separators)}
+	for idx := range rows {
+		lines = append(lines, markdownRow(row(idx)))
+	}
+	return lines
+}
+
+func markdownRow(columns []string) string {
+	escaped := make([]string, len(columns))
+	for idx, column := range columns {
+		escaped[idx] = strings.ReplaceAll(column, "|", "\\|")
+	}
+	return "| " + strings.Join(escaped, " | ") + " |"
+}
+
+// writePatch writes an UPDATE moving the participants of each suggestion to its new club. The patch runs in a single
+// transaction and is meant to be reviewed before applying it.
+func writePatch(w io.Writer, suggestions []types.MappingSuggestion) error {
+	lines := []string{"-- club name mappings suggested by cmd/clubnames, review before applying", "BEGIN;", ""}
+	for _, suggestion := range suggestions {
+		lines = append(lines,
+			fmt.Sprintf("-- %s: %s, %d participants from %s to %s (score %.2f, current %.2f)",
+				suggestion.Reason, strings.ReplaceAll(suggestion.RawName, "\n", " "), suggestion.Participants,
+				formatClub(suggestion.From), formatClub(suggestion.To), suggestion.Score, suggestion.CurrentScore),
+			fmt.Sprintf("UPDATE participant SET club_id = %d WHERE club_id = %d AND %s = ANY(club_names);",
+				suggestion.To.ID, suggestion.From.ID, pq.QuoteLiteral(suggestion.RawName)),
+			"")
+	}
+	lines = append(lines, "COMMIT;")
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func formatClub(club *types.Entity) string {
+	return fmt.Sprintf("%d (%s)", club.ID, club.Name)
+}
+
+var (
+	clubName  string
+	minScore  float64
+	clusters  bool
+	format    string
+	output    string
+	patchFile string
+
+	verbose bool
+)
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/service"
+	"github.com/iagocanalejas/rstats/internal/types"
+	"github.com/iagocanalejas/rstats/internal/utils/assert"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	pflag.StringVarP(&clubName, "club", "c", "", "club ID or name")
+	pflag.StringVarP(&rivalName, "rival", "r", "", "rival club ID or name")
+	pflag.StringVarP(&leagueName, "league", "l", "", "league ID, name or symbol to limit the races to")
+	pflag.StringVarP(&flagName, "flag", "f", "", "flag ID or name to limit the races to")
+	pflag.StringVarP(&gender, "gender", "g", types.GENDER_MALE, "gender filter")
+	pflag.StringVar(&category, "category", types.CATEGORY_ABSOLUT, "category filter")
+	pflag.IntVarP(&day, "day", "d", 0, "day of the race for multiday races")
+	pflag.BoolVar(&leaguesOnly, "leagues-only", false, "only races from a league")
+	pflag.BoolVar(&applyPenalties, "penalties", false, "add the time penalties to the participants' times")
+	pflag.IntVarP(&latest, "latest", "n", 5, "number of latest meetings shown")
+	pflag.BoolVarP(&verbose, "verbose", "v", false, "define log level to DEBUG")
+	dbFlags := db.RegisterFlags(pflag.CommandLine)
+	pflag.Parse()
+
+	assert.Assert(clubName != "" && rivalName != "", "both a club and a rival are required")
+
+	if verbose {
+		prettylog.SetLevel(prettylog.DEBUG)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dbConfig, err := dbFlags.Config()
+	assert.NoError(err, "loading database config: %v", err)
+
+	s, err := service.Init(ctx, dbConfig)
+	assert.NoError(err, "initializing service: %v", err)
+
+	params := &service.GetHeadToHeadParams{
+		Gender:          gender,
+		Category:        category,
+		Day:             int16(day),
+		OnlyLeagueRaces: leaguesOnly,
+		ApplyPenalties:  applyPenalties,
+		Latest:          latest,
+	}
+
+	params.Club, err = s.ResolveClub(ctx, clubName)
+	assert.NoError(err, "invalid club=%s: %v", clubName, err)
+	params.Rival, err = s.ResolveClub(ctx, rivalName)
+	assert.NoError(err, "invalid rival=%s: %v", rivalName, err)
+	if leagueName != "" {
+		params.League, err = s.ResolveLeague(ctx, leagueName)
+		assert.NoError(err, "invalid league=%s: %v", leagueName, err)
+	}
+	if flagName != "" {
+		params.Flag, err = s.ResolveFlag(ctx, flagName)
+		assert.NoError(err, "invalid flag=%s: %v", flagName, err)
+	}
+
+	h, err := s.GetHeadToHead(ctx, params)
+	assert.NoError(err, "loading head to head: %v", err)
+
+	if len(h.Meetings) == 0 {
+		prettylog.Info("club=%s and rival=%s never met", h.Club.Name, h.Rival.Name)
+		return
+	}
+	printHeadToHead(h)
+}
+
+func printHeadToHead(h *types.HeadToHead) {
+	fmt.Printf("%s vs %s\n\n", h.Club.Name, h.Rival.Name)
+	fmt.Printf("Meetings: %d\tWins: %d\tLosses: %d\tDraws: %d\n", len(h.Meetings), h.Wins, h.Losses, h.Draws)
+	fmt.Printf("Average gap: %s\tTrend: %s per year\n\n", gapText(h.AverageGap), gapText(h.Trend))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Year\tMeetings\tWins\tAverage gap")
+	for _, record := range h.Years {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\n", record.Year, record.Meetings, record.Wins, gapText(record.AverageGap))
+	}
+	w.Flush()
+
+	if latest <= 0 {
+		return
+	}
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "Date\tRace\tClub\tRival\tGap")
+	for idx := len(h.Meetings) - 1; idx >= max(len(h.Meetings)-latest, 0); idx-- {
+		meeting := h.Meetings[idx]
+		fmt.Fprintf(w, "%s\t%d %s\t%s\t%s\t%s\n",
+			meeting.Date.Format(time.DateOnly), meeting.RaceID, meeting.RaceName,
+			types.FormatLapTime(meeting.ClubTime), types.FormatLapTime(meeting.RivalTime), gapText(meeting.Gap))
+	}
+}
+
+// gapText shows a signed gap in seconds, negative when the club is faster.
+func gapText(gap time.Duration) string {
+	return fmt.Sprintf("%+.2fs", gap.Seconds())
+}
+
+var (
+	clubName       string
+	rivalName      string
+	leagueName     string
+	flagName       string
+	gender         string
+	category       string
+	day            int
+	leaguesOnly    bool
+	applyPenalties bool
+	latest         int
+
+	verbose bool
+)
@@ -1,138 +1,148 @@
 package main
 
 import (
-	"sync"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
 
+	"github.com/iagocanalejas/rstats/internal/db"
 	"github.com/iagocanalejas/rstats/internal/service"
 	"github.com/iagocanalejas/rstats/internal/types"
 	"github.com/iagocanalejas/rstats/internal/utils/arrays"
 	"github.com/iagocanalejas/rstats/internal/utils/assert"
 	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"github.com/iagocanalejas/rstats/pkg/outliers"
 	"github.com/spf13/pflag"
 )
 
-type ParticipantOutlier struct {
-	RaceID        int64
-	ParticipantID int64
-	AVGSpeed      float64
-	Speed         float64
-}
-
 func main() {
-	pflag.Float64VarP(&threshold, "threshold", "t", 0.3, "threshold for outlier detection")
-	pflag.Int64SliceVar(&excludedRaceIDs, "exclude", []int64{}, "define ignored race IDs")
-	pflag.BoolVarP(&limits, "limits", "l", false, "apply spped limits (8.0, 20.0) for outlier detection")
+	config := outliers.DefaultConfig()
+	available := []string{outliers.MEAN, outliers.LIMITS, outliers.MAD, outliers.IQR, outliers.ZSCORE, outliers.CONSISTENCY}
+
+	pflag.StringSliceVarP(&detectors, "detectors", "d", []string{outliers.MEAN}, fmt.Sprintf("outlier detectors. Available detectors: %s", strings.Join(available, ", ")))
+	pflag.StringVar(&mode, "mode", outliers.ANY, "combination of the detectors, any or all of them have to flag a speed")
+	pflag.StringVar(&category, "category", types.CATEGORY_ABSOLUT, "category filter, all of them when empty")
+	pflag.Float64VarP(&config.MeanThreshold, "threshold", "t", config.MeanThreshold, "threshold for the mean detector")
+	pflag.Float64Var(&config.MADThreshold, "mad-threshold", config.MADThreshold, "modified z-score threshold for the mad detector")
+	pflag.Float64Var(&config.IQRFactor, "iqr-factor", config.IQRFactor, "interquartile ranges outside the quartiles for the iqr detector")
+	pflag.Float64Var(&config.ZThreshold, "z-threshold", config.ZThreshold, "z-score threshold for the zscore detector")
+	pflag.Float64Var(&config.ConsistencyThreshold, "consistency-threshold", config.ConsistencyThreshold, "modified z-score threshold for the consistency detector")
+	pflag.StringVar(&reviewsFile, "reviews", outliers.DEFAULT_REVIEWS_FILE, "outlier reviews file")
+	pflag.Int64SliceVar(&confirmed, "confirm", []int64{}, "participant IDs to mark as confirmed errors in the reviews file")
+	pflag.Int64SliceVar(&legit, "legit", []int64{}, "participant IDs to mark as legit in the reviews file")
+	pflag.BoolVar(&all, "all", false, "also report the outliers already reviewed as legit")
+	pflag.StringVarP(&format, "format", "f", outliers.FORMAT_MARKDOWN, "report format. Available formats: json, csv, markdown")
+	pflag.StringVarP(&output, "output", "o", "", "file to write the report to, stdout when not given")
+	pflag.BoolVarP(&limits, "limits", "l", false, fmt.Sprintf("apply speed limits (%.1f, %.1f) for outlier detection", config.MinSpeed, config.MaxSpeed))
 	pflag.BoolVarP(&verbose, "verbose", "v", false, "define log level to DEBUG")
+	dbFlags := db.RegisterFlags(pflag.CommandLine)
 	pflag.Parse()
 
+	if limits && !arrays.Contains(detectors, outliers.LIMITS) {
+		detectors = append(detectors, outliers.LIMITS)
+	}
+
 	if verbose {
 		prettylog.SetLevel(prettylog.DEBUG)
-		prettylog.Debug("threshold=%f, excludedRaceIDs=%v", threshold, excludedRaceIDs)
+		prettylog.Debug("detectors=%v, mode=%s, config=%+v", detectors, mode, *config)
 	}
 
-	s := service.Init()
+	reviews, err := outliers.LoadReviews(reviewsFile)
+	assert.NoError(err, "loading reviews: %v", err)
+	if len(confirmed) > 0 || len(legit) > 0 {
+		markReviews(reviews)
+		return
+	}
 
-	participants, err := s.GetParticipantsWithSpeed()
-	assert.NoError(err, "loading participants with speed")
-	prettylog.Info("grouped into %d", len(participants))
+	detector, err := outliers.New(detectors, mode, config)
+	assert.NoError(err, "building detectors: %v", err)
 
-	batchSize := 500
-	ProcessOutliers(participants, batchSize)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	// batchSizes := []int{100, 500, 1000, 5000}
-	// for _, batchSize := range batchSizes {
-	// 	start := time.Now()
-	// 	ProcessOutliers(participants, batchSize)
-	// 	elapsed := time.Since(start)
-	// 	fmt.Printf("Batch size: %d, Time taken: %s\n", batchSize, elapsed)
-	// }
-}
+	dbConfig, err := dbFlags.Config()
+	assert.NoError(err, "loading database config: %v", err)
 
-func ProcessOutliers(participants [][]*types.Participant, batchSize int) {
-	outliers := make([]*ParticipantOutlier, 0)
-
-	outliersChan := make(chan *ParticipantOutlier)
-	doneChan := make(chan bool)
-	go func() {
-		for {
-			outlier, ok := <-outliersChan
-			if !ok {
-				// ensure we close the doneChan when the channel is done processing
-				doneChan <- true
-				break
+	s, err := service.Init(ctx, dbConfig)
+	assert.NoError(err, "initializing service: %v", err)
+
+	participants, err := s.GetParticipantsWithSpeed(ctx, category)
+	assert.NoError(err, "loading participants with speed: %v", err)
+	prettylog.Info("grouped into %d", len(participants))
+
+	// confirmed errors are left out so they don't skew the races they belong to
+	confirmedErrors := reviews.ConfirmedErrors()
+	races := make([][]*types.Participant, 0, len(participants))
+	for _, group := range participants {
+		race := make([]*types.Participant, 0, len(group))
+		for _, participant := range group {
+			if !arrays.Contains(confirmedErrors, participant.ID) {
+				race = append(race, participant)
 			}
-			prettylog.Info("found outlier: %+v", *outlier)
-			outliers = append(outliers, outlier)
 		}
-		prettylog.Info("finished processing all outliers from channel")
-	}()
-
-	var wg sync.WaitGroup
-	for i := 0; i < len(participants); i += batchSize {
-		j := i + batchSize
-		if j > len(participants) {
-			j = len(participants)
+		if len(race) > 0 {
+			races = append(races, race)
 		}
-		wg.Add(1)
-		go func(start, end int) {
-			defer wg.Done()
-
-			for _, group := range participants[start:end] {
-				detectRaceOutliers(outliersChan, group, threshold)
-			}
-		}(i, j)
 	}
 
-	wg.Wait()
-	close(outliersChan)
-	<-doneChan // wait for the channel to finish processing
-
-	prettylog.Info("found %d outliers", len(outliers))
+	found := make([]outliers.Outlier, 0)
+	for _, outlier := range detector.Detect(races) {
+		if all || reviews.Status(outlier.ParticipantID) != outliers.REVIEW_LEGIT {
+			found = append(found, outlier)
+		}
+	}
+	prettylog.Info("found %d outliers with detector=%s", len(found), detector.Name())
 
-	races := make([]int64, 0)
-	for _, outlier := range outliers {
-		if !arrays.Contains(races, outlier.RaceID) {
-			races = append(races, outlier.RaceID)
+	raceNames := make(map[int64]string)
+	for _, outlier := range found {
+		if _, ok := raceNames[outlier.RaceID]; ok {
+			continue
 		}
+		race, err := s.GetRaceByID(ctx, outlier.RaceID)
+		assert.NoError(err, "loading race=%d: %v", outlier.RaceID, err)
+		raceNames[outlier.RaceID] = race.Name
+	}
+	prettylog.Info("grouped in %d races", len(raceNames))
+
+	w := os.Stdout
+	if output != "" {
+		w, err = os.Create(output)
+		assert.NoError(err, "creating report file=%s: %v", output, err)
+		defer w.Close()
 	}
-	prettylog.Info("grouped in %d races", len(races))
-	prettylog.Info("\nraces: %+v", races)
+
+	report := outliers.NewReport(found, races, raceNames, reviews)
+	err = outliers.WriteReport(w, format, report)
+	assert.NoError(err, "writing report: %v", err)
 }
 
-func detectRaceOutliers(channel chan *ParticipantOutlier, group []*types.Participant, threshold float64) {
-	sum := 0.0
-	for _, participant := range group {
-		sum += *participant.Speed
+func markReviews(reviews *outliers.Reviews) {
+	for _, id := range confirmed {
+		err := reviews.Mark(id, outliers.REVIEW_ERROR)
+		assert.NoError(err, "marking participant=%d: %v", id, err)
 	}
-	mean := sum / float64(len(group))
-	for _, participant := range group {
-		if arrays.Contains(excludedRaceIDs, participant.RaceID) {
-			return
-		}
-		if *participant.Speed > mean*(threshold+1) || *participant.Speed < mean*(1-threshold) {
-			channel <- &ParticipantOutlier{
-				RaceID:        participant.RaceID,
-				ParticipantID: participant.ID,
-				AVGSpeed:      mean,
-				Speed:         *participant.Speed,
-			}
-			continue
-		}
-		if limits && (*participant.Speed > 20.0 || *participant.Speed < 8.0) {
-			channel <- &ParticipantOutlier{
-				RaceID:        participant.RaceID,
-				ParticipantID: participant.ID,
-				AVGSpeed:      0.0,
-				Speed:         *participant.Speed,
-			}
-			continue
-		}
+	for _, id := range legit {
+		err := reviews.Mark(id, outliers.REVIEW_LEGIT)
+		assert.NoError(err, "marking participant=%d: %v", id, err)
 	}
+
+	err := reviews.Save()
+	assert.NoError(err, "saving reviews: %v", err)
+	prettylog.Info("marked %d confirmed errors and %d legit outliers in %s", len(confirmed), len(legit), reviewsFile)
 }
 
 var (
-	threshold       float64
-	excludedRaceIDs []int64
-	limits          bool
-	verbose         bool
+	detectors   []string
+	mode        string
+	category    string
+	reviewsFile string
+	confirmed   []int64
+	legit       []int64
+	all         bool
+	format      string
+	output      string
+	limits      bool
+	verbose     bool
 )
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/service"
+	"github.com/iagocanalejas/rstats/internal/types"
+	"github.com/iagocanalejas/rstats/internal/utils/assert"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	pflag.Int64VarP(&raceID, "race", "r", 0, "race ID to show the splits of each participant")
+	pflag.StringVarP(&clubName, "club", "c", "", "club ID or name to show the pacing profile of")
+	pflag.StringVarP(&leagueName, "league", "l", "", "league ID, name or symbol to limit the profile to")
+	pflag.StringVarP(&gender, "gender", "g", types.GENDER_MALE, "gender filter")
+	pflag.StringVar(&category, "category", types.CATEGORY_ABSOLUT, "category filter")
+	pflag.Int16VarP(&year, "year", "y", 0, "season of the profile, all of them when not given")
+	pflag.BoolVarP(&verbose, "verbose", "v", false, "define log level to DEBUG")
+	dbFlags := db.RegisterFlags(pflag.CommandLine)
+	pflag.Parse()
+
+	assert.Assert(raceID > 0 || clubName != "", "either a race or a club is required")
+
+	if verbose {
+		prettylog.SetLevel(prettylog.DEBUG)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dbConfig, err := dbFlags.Config()
+	assert.NoError(err, "loading database config: %v", err)
+
+	s, err := service.Init(ctx, dbConfig)
+	assert.NoError(err, "initializing service: %v", err)
+
+	if raceID > 0 {
+		race, pacings, err := s.GetRacePacing(ctx, raceID)
+		assert.NoError(err, "loading race pacing: %v", err)
+		printRacePacing(race, pacings)
+		return
+	}
+
+	club, err := s.ResolveClub(ctx, clubName)
+	assert.NoError(err, "invalid club=%s: %v", clubName, err)
+
+	var league *types.League
+	if leagueName != "" {
+		league, err = s.ResolveLeague(ctx, leagueName)
+		assert.NoError(err, "invalid league=%s: %v", leagueName, err)
+	}
+
+	profiles, err := s.GetClubPacing(ctx, &service.GetClubPacingParams{
+		Club:     club,
+		League:   league,
+		Gender:   gender,
+		Category: category,
+		Year:     year,
+	})
+	assert.NoError(err, "loading club pacing: %v", err)
+	printProfiles(club, profiles)
+}
+
+func printRacePacing(race *types.Race, pacings []service.ParticipantPacing) {
+	fmt.Printf("%d (%s) %s\n\n", race.ID, race.Date, race.Name)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	header := []string{"Club"}
+	if len(pacings) > 0 {
+		for _, split := range pacings[0].Pacing.Splits {
+			header = append(header, fmt.Sprintf("Lap %d", split.Lap))
+		}
+	}
+	fmt.Fprintln(w, strings.Join(append(header, "Speed", "2nd/1st", "Fade"), "\t"))
+
+	for _, p := range pacings {
+		columns := []string{p.Participant.Club.Name}
+		for _, split := range p.Pacing.Splits {
+			columns = append(columns, fmt.Sprintf("%s (%.2f)", types.FormatLapTime(split.Time), split.Speed))
+		}
+		columns = append(columns,
+			fmt.Sprintf("%.2f", p.Pacing.Speed),
+			fmt.Sprintf("%.3f", p.Pacing.HalvesRatio),
+			fmt.Sprintf("%+.2f%%", p.Pacing.Fade),
+		)
+		fmt.Fprintln(w, strings.Join(columns, "\t"))
+	}
+}
+
+func printProfiles(club *types.Entity, profiles []types.PacingProfile) {
+	if len(profiles) == 0 {
+		prettylog.Info("no races with complete laps found for club=%s", club.Name)
+		return
+	}
+
+	fmt.Printf("%s\n\n", club.Name)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "Laps\tRaces\tRelative lap speeds\t2nd/1st\tFade")
+	for _, profile := range profiles {
+		speeds := make([]string, len(profile.RelativeSpeeds))
+		for idx, speed := range profile.RelativeSpeeds {
+			speeds[idx] = fmt.Sprintf("%.3f", speed)
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%.3f\t%+.2f%%\n", profile.Laps, profile.Races, strings.Join(speeds, " "), profile.HalvesRatio, profile.Fade)
+	}
+}
+
+var (
+	raceID     int64
+	clubName   string
+	leagueName string
+	gender     string
+	category   string
+	year       int16
+
+	verbose bool
+)
@@ -1,82 +1,146 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
 
+	"github.com/iagocanalejas/rstats/internal/db"
 	"github.com/iagocanalejas/rstats/internal/service"
 	"github.com/iagocanalejas/rstats/internal/types"
+	"github.com/iagocanalejas/rstats/internal/utils/arrays"
 	"github.com/iagocanalejas/rstats/internal/utils/assert"
 	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"github.com/iagocanalejas/rstats/pkg/outliers"
 	"github.com/iagocanalejas/rstats/pkg/plotter"
 	"github.com/spf13/pflag"
 )
 
 func main() {
-	pflag.StringVarP(&plotType, "type", "t", plotter.BOXPLOT, fmt.Sprintf("plot type. Available types: %s", strings.Join([]string{plotter.BOXPLOT, plotter.LINE, plotter.NTH_SPEED}, ", ")))
+	pflag.StringVarP(&plotType, "type", "t", plotter.BOXPLOT, fmt.Sprintf("plot type. Available types: %s", strings.Join([]string{plotter.BOXPLOT, plotter.LINE, plotter.NTH_SPEED, plotter.HISTOGRAM, plotter.SCATTER, plotter.RACE}, ", ")))
 	pflag.IntVarP(&index, "index", "i", 0, "position to plot the speeds in 'nth' charts")
-	pflag.IntVarP(&clubID, "club", "c", 0, "club ID for which to load the data")
-	pflag.IntVarP(&leagueID, "league", "l", 0, "league ID for which to load the data")
-	pflag.IntVarP(&flagID, "flag", "f", 0, "flagID for which to load the data")
+	pflag.Int64Var(&raceID, "race", 0, "race ID to plot in 'race' charts")
+	pflag.BoolVar(&splits, "splits", false, "plot the speed of each lap instead of the gap to the winner in 'race' charts")
+	pflag.IntVar(&bins, "bins", plotter.DEFAULT_BINS, "number of bins of 'histogram' charts")
+	pflag.BoolVar(&density, "kde", false, "overlay a kernel density estimate on 'histogram' charts")
+	pflag.StringVar(&trend, "trend", plotter.TREND_NONE, fmt.Sprintf("trend line of 'scatter' charts. Available trends: %s", strings.Join([]string{plotter.TREND_LINEAR, plotter.TREND_LOESS}, ", ")))
+	pflag.StringSliceVarP(&clubNames, "club", "c", nil, "club IDs or names for which to load the data, several clubs are compared in 'boxplot', 'line' and 'histogram' charts")
+	pflag.StringVarP(&leagueName, "league", "l", "", "league ID, name or symbol for which to load the data")
+	pflag.StringVarP(&flagName, "flag", "f", "", "flag ID or name for which to load the data")
 	pflag.StringVarP(&gender, "gender", "g", types.GENDER_MALE, "gender filter")
 	pflag.StringVar(&category, "category", types.CATEGORY_ABSOLUT, "category filter")
 	pflag.VarP(&years, "years", "y", "years to include in the data (can specify multiple times)")
 	pflag.IntVarP(&day, "day", "d", 0, "day of the race for multiday races")
 	pflag.BoolVar(&leaguesOnly, "leagues-only", false, "only races from a league")
+	pflag.StringVar(&branches, "branches", types.BRANCH_ALL, fmt.Sprintf("branch teams filter. Available modes: %s", strings.Join([]string{types.BRANCH_MAIN, types.BRANCH_ONLY, types.BRANCH_SEPARATED}, ", ")))
 	pflag.BoolVar(&branchTeams, "branch-teams", false, "filter only branch teams")
+	err := pflag.CommandLine.MarkDeprecated("branch-teams", "use --branches branch instead")
+	assert.NoError(err, "deprecating branch-teams: %v", err)
 	pflag.BoolVarP(&normalize, "normalize", "n", false, "exclude outliers based on the speeds' standard deviation")
+	pflag.StringVar(&reviewsFile, "reviews", outliers.DEFAULT_REVIEWS_FILE, "outlier reviews file, confirmed errors are excluded when normalizing")
+	pflag.BoolVar(&applyPenalties, "penalties", false, "add the time penalties to the participants' times")
 	pflag.StringVarP(&output, "output", "o", "", "saves the output plot")
+	pflag.StringVar(&format, "format", "", fmt.Sprintf("output format, taken from the output extension when not given. Available formats: %s", strings.Join([]string{plotter.FORMAT_PNG, plotter.FORMAT_SVG, plotter.FORMAT_PDF, plotter.FORMAT_EPS}, ", ")))
+	pflag.Float64Var(&width, "width", plotter.DEFAULT_WIDTH, "plot width in inches")
+	pflag.Float64Var(&height, "height", plotter.DEFAULT_HEIGHT, "plot height in inches")
+	pflag.IntVar(&dpi, "dpi", plotter.DEFAULT_DPI, "resolution of png plots")
+	pflag.BoolVar(&headless, "headless", false, "never open a viewer, plots without an output are saved to the temporary directory")
 	pflag.BoolVarP(&verbose, "verbose", "v", false, "define log level to DEBUG")
+	dbFlags := db.RegisterFlags(pflag.CommandLine)
+	pflag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	s := service.Init()
-	config := parseArgs(s)
+	dbConfig, err := dbFlags.Config()
+	assert.NoError(err, "loading database config: %v", err)
+
+	s, err := service.Init(ctx, dbConfig)
+	assert.NoError(err, "initializing service: %v", err)
+	config := parseArgs(ctx, s)
 
 	if verbose {
 		prettylog.SetLevel(prettylog.DEBUG)
 		prettylog.Debug("config=%v", *config)
 	}
 
-	plotter.PlotStats(s, config)
+	err = plotter.PlotStats(ctx, s, config)
+	assert.NoError(err, "plotting stats: %v", err)
 }
 
-func parseArgs(service *service.Service) *plotter.PlotConfig {
-	pflag.Parse()
-
+func parseArgs(ctx context.Context, service *service.Service) *plotter.PlotConfig {
 	assert.Contains(gender, []string{types.GENDER_ALL, types.GENDER_MALE, types.GENDER_FEMALE, types.GENDER_MIX}, "invalid gender=%s", gender)
 	assert.Contains(category, []string{types.CATEGORY_ABSOLUT, types.CATEGORY_SCHOOL, types.CATEGORY_VETERAN}, "invalid category=%s", category)
-	assert.Contains(plotType, []string{plotter.BOXPLOT, plotter.LINE, plotter.NTH_SPEED}, "invalid plotType=%s", plotType)
+	assert.Contains(plotType, []string{plotter.BOXPLOT, plotter.LINE, plotter.NTH_SPEED, plotter.HISTOGRAM, plotter.SCATTER, plotter.RACE}, "invalid plotType=%s", plotType)
+	if branchTeams {
+		branches = types.BRANCH_ONLY
+	}
+	assert.Contains(branches, []string{types.BRANCH_ALL, types.BRANCH_MAIN, types.BRANCH_ONLY, types.BRANCH_SEPARATED}, "invalid branches=%s", branches)
+	assert.Assert(plotType != plotter.NTH_SPEED || branches != types.BRANCH_SEPARATED, "plotType=%s does not support branches=%s", plotType, branches)
+	assert.Assert(plotType != plotter.SCATTER || branches != types.BRANCH_SEPARATED, "plotType=%s does not support branches=%s", plotType, branches)
 	assert.Assert(plotType != plotter.NTH_SPEED || len(years) > 0, "plotType=%s requires at least one year", plotType)
 	assert.Assert(plotType != plotter.NTH_SPEED || index > 0, "plotType=%s requires an index", plotType)
+	assert.Assert(len(clubNames) <= 1 || plotType == plotter.BOXPLOT || plotType == plotter.LINE || plotType == plotter.HISTOGRAM, "plotType=%s does not support several clubs", plotType)
+	assert.Assert(len(clubNames) <= 1 || branches != types.BRANCH_SEPARATED, "several clubs do not support branches=%s", branches)
 
-	validBoxplot := plotType == plotter.BOXPLOT && (clubID > 0 || leagueID > 0 || flagID > 0)
-	validNthPlot := plotType == plotter.NTH_SPEED && leagueID > 0 && len(years) > 0 && index > 0
-	validLinePlot := plotType == plotter.LINE && (clubID > 0 || flagID > 0) && len(years) > 0
-	assert.Assert(validBoxplot || validNthPlot || validLinePlot, "invalid plot configuration")
+	validBoxplot := plotType == plotter.BOXPLOT && (len(clubNames) > 0 || leagueName != "" || flagName != "")
+	validNthPlot := plotType == plotter.NTH_SPEED && leagueName != "" && len(years) > 0 && index > 0
+	validLinePlot := plotType == plotter.LINE && (len(clubNames) > 0 || flagName != "") && len(years) > 0
+	validHistogram := plotType == plotter.HISTOGRAM && (len(clubNames) > 0 || leagueName != "" || flagName != "") && bins > 0
+	validScatter := plotType == plotter.SCATTER && (len(clubNames) > 0 || leagueName != "" || flagName != "")
+	validRacePlot := plotType == plotter.RACE && raceID > 0
+	assert.Assert(validBoxplot || validNthPlot || validLinePlot || validHistogram || validScatter || validRacePlot, "invalid plot configuration")
+	assert.Contains(trend, []string{plotter.TREND_NONE, plotter.TREND_LINEAR, plotter.TREND_LOESS}, "invalid trend=%s", trend)
+
+	assert.Assert(format == "" || arrays.Contains([]string{plotter.FORMAT_PNG, plotter.FORMAT_SVG, plotter.FORMAT_PDF, plotter.FORMAT_EPS}, format), "invalid format=%s", format)
+	assert.Assert(width > 0 && height > 0 && dpi > 0, "invalid size width=%f height=%f dpi=%d", width, height, dpi)
+	if extension := strings.TrimPrefix(filepath.Ext(output), "."); format != "" && extension != "" && extension != format {
+		prettylog.Info("output=%s will be written as %s", output, format)
+	}
+	if !headless && output == "" && os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		prettylog.Info("no display found, running headless")
+		headless = true
+	}
 
 	var err error
+	clubIDs := make([]int64, 0, len(clubNames))
+	clubs := make([]*types.Entity, 0, len(clubNames))
+	for _, clubName := range clubNames {
+		club, err := service.ResolveClub(ctx, clubName)
+		assert.NoError(err, "invalid club=%s: %v", clubName, err)
+		assert.Assert(!arrays.Contains(clubIDs, club.ID), "duplicated club=%s", clubName)
+		prettylog.Info("club=%s resolved to %d (%s)", clubName, club.ID, club.Name)
+
+		clubIDs = append(clubIDs, club.ID)
+		clubs = append(clubs, club)
+	}
+
+	// the first club is the one compared with the rest
 	var club *types.Entity
-	if clubID > 0 {
-		club, err = service.GetClubByID(int64(clubID))
-		assert.NotNil(club, "invalid clubID=%d", clubID)
-		assert.NoError(err, "invalid clubID=%d", clubID)
+	var rivals []*types.Entity
+	if len(clubs) > 0 {
+		club, rivals = clubs[0], clubs[1:]
 	}
 
 	var flag *types.Flag
-	if flagID > 0 {
-		flag, err = service.GetFlagByID(int64(flagID))
-		assert.NotNil(flag, "invalid flagID=%d", flagID)
-		assert.NoError(err, "invalid flagID=%d", flagID)
+	if flagName != "" {
+		flag, err = service.ResolveFlag(ctx, flagName)
+		assert.NoError(err, "invalid flag=%s: %v", flagName, err)
+		prettylog.Info("flag=%s resolved to %d (%s)", flagName, flag.ID, flag.Name)
 	}
 
 	var league *types.League
-	if leagueID > 0 {
-		league, err = service.GetLeagueByID(int64(leagueID))
-		assert.NotNil(league, "invalid leagueID=%d", leagueID)
-		assert.NoError(err, "invalid leagueID=%d", leagueID)
-
-		if branchTeams {
-			prettylog.Info("branch_teams is not supported with leagues, ignoring it")
-			branchTeams = false
+	if leagueName != "" {
+		league, err = service.ResolveLeague(ctx, leagueName)
+		assert.NoError(err, "invalid league=%s: %v", leagueName, err)
+		prettylog.Info("league=%s resolved to %d (%s)", leagueName, league.ID, league.Name)
+
+		if branches != types.BRANCH_ALL {
+			prettylog.Info("branches=%s is not supported with leagues, ignoring it", branches)
+			branches = types.BRANCH_ALL
 		}
 
 		if league.Gender != nil && gender != *league.Gender {
@@ -90,39 +154,73 @@ func parseArgs(service *service.Service) *plotter.PlotConfig {
 		}
 	}
 
+	var excluded []int64
+	if normalize && reviewsFile != "" {
+		reviews, err := outliers.LoadReviews(reviewsFile)
+		assert.NoError(err, "loading outlier reviews: %v", err)
+		excluded = reviews.ConfirmedErrors()
+		prettylog.Info("excluding %d confirmed outliers", len(excluded))
+	}
+
 	return &plotter.PlotConfig{
-		Index:       index,
-		Club:        club,
-		League:      league,
-		Flag:        flag,
-		PlotType:    plotType,
-		Gender:      gender,
-		Category:    category,
-		Years:       years,
-		Day:         day,
-		Normalize:   normalize,
-		LeaguesOnly: leaguesOnly,
-		BranchTeams: branchTeams,
-		Output:      output,
+		Index:          index,
+		RaceID:         raceID,
+		Club:           club,
+		Rivals:         rivals,
+		League:         league,
+		Flag:           flag,
+		PlotType:       plotType,
+		Gender:         gender,
+		Category:       category,
+		Years:          years,
+		Day:            day,
+		Normalize:      normalize,
+		Excluded:       excluded,
+		LeaguesOnly:    leaguesOnly,
+		Branch:         branches,
+		ApplyPenalties: applyPenalties,
+		Bins:           bins,
+		Density:        density,
+		Trend:          trend,
+		Splits:         splits,
+		Output:         output,
+		Format:         format,
+		Width:          width,
+		Height:         height,
+		DPI:            dpi,
+		Headless:       headless,
 	}
 }
 
 var (
-	plotType string
-	index    int
-	clubID   int
-	leagueID int
-	flagID   int
-	gender   string
-	category string
-	years    yearsFlag
-	day      int
-
-	leaguesOnly bool
-	branchTeams bool
-	normalize   bool
-	output      string
-	verbose     bool
+	plotType   string
+	index      int
+	raceID     int64
+	splits     bool
+	bins       int
+	density    bool
+	trend      string
+	clubNames  []string
+	leagueName string
+	flagName   string
+	gender     string
+	category   string
+	years      yearsFlag
+	day        int
+
+	leaguesOnly    bool
+	branches       string
+	branchTeams    bool
+	normalize      bool
+	reviewsFile    string
+	applyPenalties bool
+	output         string
+	format         string
+	width          float64
+	height         float64
+	dpi            int
+	headless       bool
+	verbose        bool
 )
 
 type yearsFlag []int
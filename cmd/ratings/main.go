@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/service"
+	"github.com/iagocanalejas/rstats/internal/types"
+	"github.com/iagocanalejas/rstats/internal/utils/assert"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	pflag.StringVarP(&system, "system", "s", types.RATING_GLICKO2, "rating system, one of elo or glicko2")
+	pflag.StringVarP(&clubName, "club", "c", "", "club ID or name to show the rating history of")
+	pflag.StringVarP(&gender, "gender", "g", types.GENDER_MALE, "gender filter")
+	pflag.StringVar(&category, "category", types.CATEGORY_ABSOLUT, "category filter")
+	pflag.IntVarP(&limit, "limit", "n", 25, "number of clubs shown in the leaderboard, all of them when zero")
+	pflag.IntVar(&minRaces, "min-races", 10, "races needed to appear in the leaderboard")
+	pflag.BoolVarP(&verbose, "verbose", "v", false, "define log level to DEBUG")
+	dbFlags := db.RegisterFlags(pflag.CommandLine)
+	pflag.Parse()
+
+	if verbose {
+		prettylog.SetLevel(prettylog.DEBUG)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dbConfig, err := dbFlags.Config()
+	assert.NoError(err, "loading database config: %v", err)
+
+	s, err := service.Init(ctx, dbConfig)
+	assert.NoError(err, "initializing service: %v", err)
+
+	var club *types.Entity
+	if clubName != "" {
+		club, err = s.ResolveClub(ctx, clubName)
+		assert.NoError(err, "invalid club=%s: %v", clubName, err)
+	}
+
+	ratings, err := s.GetClubRatings(ctx, &service.GetClubRatingsParams{System: system, Gender: gender, Category: category})
+	assert.NoError(err, "computing ratings: %v", err)
+
+	if club != nil {
+		rating := ratings.Club(club.ID)
+		if rating == nil {
+			prettylog.Info("club=%s has no rated races", club.Name)
+			return
+		}
+		printHistory(rating)
+		return
+	}
+
+	leaderboard := ratings.Leaderboard(minRaces)
+	if limit > 0 && len(leaderboard) > limit {
+		leaderboard = leaderboard[:limit]
+	}
+	printLeaderboard(ratings, leaderboard)
+}
+
+func printLeaderboard(ratings *types.Ratings, leaderboard []types.ClubRating) {
+	fmt.Printf("%s %s (%s)\n\n", ratings.Gender, ratings.Category, ratings.System)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "Pos\tClub\tRating\tRD\tRaces\tLast race")
+	for idx, rating := range leaderboard {
+		fmt.Fprintf(w, "%d\t%s\t%.0f\t%s\t%d\t%s\n",
+			idx+1, rating.Club.Name, rating.Rating, deviationText(&rating), rating.Races, rating.LastRace.Format(time.DateOnly))
+	}
+}
+
+func printHistory(rating *types.ClubRating) {
+	fmt.Printf("%s: %.0f %s after %d races\n\n", rating.Club.Name, rating.Rating, deviationText(rating), rating.Races)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "Date\tRace\tRating\tRD")
+	for _, point := range rating.History {
+		fmt.Fprintf(w, "%s\t%d\t%.0f\t%.0f\n", point.Date.Format(time.DateOnly), point.RaceID, point.Rating, point.RD)
+	}
+}
+
+// deviationText shows the uncertainty of the rating, Elo ratings have none.
+func deviationText(rating *types.ClubRating) string {
+	if rating.RD == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("±%.0f", rating.RD)
+}
+
+var (
+	system   string
+	clubName string
+	gender   string
+	category string
+	limit    int
+	minRaces int
+
+	verbose bool
+)
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/service"
+	"github.com/iagocanalejas/rstats/internal/utils/assert"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"github.com/spf13/pflag"
+)
+
+const (
+	KIND_CLUB   = "club"
+	KIND_LEAGUE = "league"
+	KIND_FLAG   = "flag"
+)
+
+func main() {
+	pflag.StringVarP(&kind, "type", "t", KIND_CLUB, fmt.Sprintf("what to look for. Available types: %s", strings.Join([]string{KIND_CLUB, KIND_LEAGUE, KIND_FLAG}, ", ")))
+	pflag.IntVarP(&limit, "limit", "n", 10, "maximum number of candidates shown, all of them when zero")
+	pflag.BoolVarP(&verbose, "verbose", "v", false, "define log level to DEBUG")
+	dbFlags := db.RegisterFlags(pflag.CommandLine)
+	pflag.Parse()
+
+	assert.Contains(kind, []string{KIND_CLUB, KIND_LEAGUE, KIND_FLAG}, "invalid type=%s", kind)
+	assert.Assert(pflag.NArg() > 0, "a name is required")
+	name := strings.Join(pflag.Args(), " ")
+
+	if verbose {
+		prettylog.SetLevel(prettylog.DEBUG)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dbConfig, err := dbFlags.Config()
+	assert.NoError(err, "loading database config: %v", err)
+
+	s, err := service.Init(ctx, dbConfig)
+	assert.NoError(err, "initializing service: %v", err)
+
+	var candidates []service.Candidate
+	switch kind {
+	case KIND_CLUB:
+		candidates, err = s.FindClubs(ctx, name)
+	case KIND_LEAGUE:
+		candidates, err = s.FindLeagues(ctx, name)
+	case KIND_FLAG:
+		candidates, err = s.FindFlags(ctx, name)
+	}
+	assert.NoError(err, "finding %s=%s: %v", kind, name, err)
+
+	if len(candidates) == 0 {
+		prettylog.Info("no %s matches %s", kind, name)
+		return
+	}
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	for _, candidate := range candidates {
+		matched := ""
+		if candidate.Matched != candidate.Name {
+			matched = fmt.Sprintf(" (%s)", candidate.Matched)
+		}
+		fmt.Printf("%6d  %.2f  %s%s\n", candidate.ID, candidate.Score, candidate.Name, matched)
+	}
+}
+
+var (
+	kind    string
+	limit   int
+	verbose bool
+)
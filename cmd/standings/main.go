@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/service"
+	"github.com/iagocanalejas/rstats/internal/types"
+	"github.com/iagocanalejas/rstats/internal/utils/assert"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	pflag.StringVarP(&leagueName, "league", "l", "", "league ID, name or symbol")
+	pflag.IntVarP(&year, "year", "y", 0, "season of the standings")
+	pflag.StringVarP(&gender, "gender", "g", types.GENDER_MALE, "gender filter")
+	pflag.StringVar(&category, "category", types.CATEGORY_ABSOLUT, "category filter")
+	pflag.StringVar(&pointsFile, "points", "", "JSON file with the points table of each league")
+	pflag.BoolVar(&progression, "progression", false, "show the standings after each round")
+	pflag.BoolVarP(&verbose, "verbose", "v", false, "define log level to DEBUG")
+	dbFlags := db.RegisterFlags(pflag.CommandLine)
+	pflag.Parse()
+
+	assert.Assert(leagueName != "", "a league is required")
+	assert.Assert(year > 0, "a year is required")
+
+	if verbose {
+		prettylog.SetLevel(prettylog.DEBUG)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dbConfig, err := dbFlags.Config()
+	assert.NoError(err, "loading database config: %v", err)
+
+	s, err := service.Init(ctx, dbConfig)
+	assert.NoError(err, "initializing service: %v", err)
+
+	if pointsFile != "" {
+		pointsTables, err := service.LoadPointsTables(pointsFile)
+		assert.NoError(err, "loading points tables: %v", err)
+		s.SetPointsTables(pointsTables)
+	}
+
+	league, err := s.ResolveLeague(ctx, leagueName)
+	assert.NoError(err, "invalid league=%s: %v", leagueName, err)
+
+	standings, err := s.GetLeagueStandings(ctx, league.ID, year, gender, category)
+	assert.NoError(err, "loading standings: %v", err)
+
+	if len(standings.Rounds) == 0 {
+		prettylog.Info("no races found for league=%s year=%d", league.Name, year)
+		return
+	}
+
+	fmt.Printf("%s %d (%s %s)\n\n", standings.League.Name, standings.Year, standings.Gender, standings.Category)
+	if !progression {
+		printStandings(standings, standings.Entries, len(standings.Rounds))
+		return
+	}
+
+	for round, entries := range standings.Progression {
+		race := standings.Rounds[round]
+		fmt.Printf("Round %d: %d (%s) %s\n", round+1, race.ID, race.Date, race.Name)
+		printStandings(standings, entries, round+1)
+		fmt.Println()
+	}
+}
+
+// printStandings prints the points of each club in the first rounds, discarded results are shown in brackets.
+func printStandings(standings *types.Standings, entries []types.StandingsEntry, rounds int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	header := []string{"Pos", "Club", "Points"}
+	for round := range standings.Rounds[:rounds] {
+		header = append(header, fmt.Sprintf("R%d", round+1))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, entry := range entries {
+		columns := []string{fmt.Sprintf("%d", entry.Position), entry.Name(), fmt.Sprintf("%g", entry.Points)}
+		for _, result := range entry.Results {
+			switch {
+			case result.Position == 0:
+				columns = append(columns, "-")
+			case result.Discarded:
+				columns = append(columns, fmt.Sprintf("[%g]", result.Points))
+			default:
+				columns = append(columns, fmt.Sprintf("%g", result.Points))
+			}
+		}
+		fmt.Fprintln(w, strings.Join(columns, "\t"))
+	}
+}
+
+var (
+	leagueName  string
+	year        int
+	gender      string
+	category    string
+	pointsFile  string
+	progression bool
+
+	verbose bool
+)
@@ -1,16 +1,37 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/service"
 	"github.com/iagocanalejas/rstats/internal/utils/assert"
 	"github.com/iagocanalejas/rstats/pkg/tui"
+	"github.com/spf13/pflag"
 )
 
 func main() {
+	pflag.StringVar(&pointsFile, "points", "", "JSON file with the points table of each league")
+	dbFlags := db.RegisterFlags(pflag.CommandLine)
+	pflag.Parse()
+
 	setupFileLogger()
-	if err := tui.BuildApp().App.Run(); err != nil {
+
+	config, err := dbFlags.Config()
+	assert.NoError(err, "loading database config: %v", err)
+
+	var pointsTables service.PointsTables
+	if pointsFile != "" {
+		pointsTables, err = service.LoadPointsTables(pointsFile)
+		assert.NoError(err, "loading points tables: %v", err)
+	}
+
+	app, err := tui.BuildApp(context.Background(), config, pointsTables)
+	assert.NoError(err, "building app: %v", err)
+
+	if err := app.App.Run(); err != nil {
 		panic(err)
 	}
 }
@@ -23,3 +44,5 @@ func setupFileLogger() {
 	log.SetOutput(f)
 	log.SetFlags(log.Lshortfile | log.LstdFlags)
 }
+
+var pointsFile string
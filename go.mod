@@ -39,6 +39,6 @@ require (
 	github.com/rivo/tview v0.42.0
 	github.com/spf13/pflag v1.0.10
 	github.com/stretchr/testify v1.8.4 // indirect
-	golang.org/x/text v0.33.0 // indirect
+	golang.org/x/text v0.33.0
 	gonum.org/v1/plot v0.16.0
 )
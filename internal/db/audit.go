@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"github.com/lib/pq"
+)
+
+// AuditParticipantRow is a participant with the race fields its data is checked against.
+type AuditParticipantRow struct {
+	ID     int64 `db:"id"`
+	RaceID int64 `db:"race_id"`
+
+	ClubID    int64          `db:"club_id"`
+	ClubNames pq.StringArray `db:"club_names"`
+
+	Gender   string `db:"gender"`
+	Category string `db:"category"`
+	Distance *int   `db:"distance"`
+
+	Laps   pq.StringArray `db:"laps"`
+	Lane   *int16         `db:"lane"`
+	Series *int16         `db:"series"`
+
+	IsRetired bool `db:"retired"`
+	IsGuest   bool `db:"guest"`
+	IsAbsent  bool `db:"absent"`
+
+	RaceLaps  *int16 `db:"race_laps"`
+	RaceLanes *int16 `db:"race_lanes"`
+}
+
+// GetAuditParticipants retrieves all the participants of the races that were not cancelled, ordered by race.
+func (r *PostgresRepository) GetAuditParticipants(ctx context.Context) ([]AuditParticipantRow, error) {
+	query, args, err := sq.
+		Select("p.id", "p.race_id", "p.club_id", "COALESCE(p.club_names, '{}') as club_names",
+			"p.gender", "p.category", "p.distance", "COALESCE(p.laps, '{}') as laps", "p.lane", "p.series",
+			"p.retired", "p.guest", "p.absent",
+			"r.laps as race_laps", "r.lanes as race_lanes").
+		From("participant p").
+		Join("race r ON p.race_id = r.id").
+		Where(sq.Expr("NOT r.cancelled")).
+		OrderBy("p.race_id", "p.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+
+	prettylog.Debug("%s %v", query, args)
+
+	participants := make([]AuditParticipantRow, 0)
+	if err = r.db.SelectContext(ctx, &participants, query, args...); err != nil {
+		return nil, queryError(err, "loading audit participants")
+	}
+
+	return participants, nil
+}
+
+// GetEmptyRaceIDs retrieves the races that were not cancelled and have no participants.
+func (r *PostgresRepository) GetEmptyRaceIDs(ctx context.Context) ([]int64, error) {
+	query, args, err := sq.
+		Select("r.id").
+		From("race r").
+		Where(sq.Expr("NOT r.cancelled")).
+		Where(sq.Expr("NOT EXISTS(SELECT 1 FROM participant p WHERE p.race_id = r.id)")).
+		OrderBy("r.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+
+	raceIDs := make([]int64, 0)
+	if err = r.db.SelectContext(ctx, &raceIDs, query, args...); err != nil {
+		return nil, queryError(err, "loading empty races")
+	}
+
+	return raceIDs, nil
+}
@@ -1,34 +1,82 @@
 package db
 
 import (
+	"context"
 	"fmt"
-	"os"
 
-	"github.com/iagocanalejas/rstats/internal/utils/assert"
 	"github.com/jmoiron/sqlx"
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
-type Repository struct {
-	db *sqlx.DB
+const (
+	BACKEND_POSTGRES = "postgres"
+	BACKEND_MEMORY   = "memory"
+)
+
+// Repository is the data access layer used by the service. It is implemented by [PostgresRepository] for the
+// production database and by [MemoryRepository] for offline use from a JSON dump.
+type Repository interface {
+	GetRaceByID(ctx context.Context, raceID int64) (*RaceRow, error)
+	SearchRaces(ctx context.Context, filters *SearchRaceParams) ([]RaceRow, error)
+
+	GetParticipantsByRaceID(ctx context.Context, raceID int64) ([]ParticipantRow, error)
+	GetParticipantsWithSpeed(ctx context.Context, category string) ([]ParticipantRowWithSpeed, error)
+	GetYearSpeedsBy(ctx context.Context, params *GetYearSpeedsByParams) ([]int, *map[int][]float64, error)
+	GetDatedSpeedsBy(ctx context.Context, params *GetYearSpeedsByParams) ([]DatedSpeedRow, error)
+	GetNthSpeedsBy(ctx context.Context, params *GetNthSpeedsByParams) ([]float64, error)
+	GetParticipantLapsBy(ctx context.Context, params *GetParticipantLapsByParams) ([]ParticipantLapsRow, error)
+	GetPenaltiesByRaceID(ctx context.Context, raceID int64) ([]PenaltyRow, error)
+	GetHeadToHead(ctx context.Context, params *GetHeadToHeadParams) ([]HeadToHeadRow, error)
+	GetAuditParticipants(ctx context.Context) ([]AuditParticipantRow, error)
+	GetEmptyRaceIDs(ctx context.Context) ([]int64, error)
+
+	GetClubByID(ctx context.Context, clubID int64) (*EntityRow, error)
+	GetClubNames(ctx context.Context) ([]ClubNamesRow, error)
+	GetClubRawNames(ctx context.Context) ([]ClubRawNameRow, error)
+	GetFlagByID(ctx context.Context, flagID int64) (*FlagRow, error)
+	GetFlags(ctx context.Context) ([]FlagRow, error)
+	GetLeagueByID(ctx context.Context, leagueID int64) (*LeagueRow, error)
+	GetLeagues(ctx context.Context) ([]LeagueRow, error)
+	GetTrophyByID(ctx context.Context, trophyID int64) (*TrophyRow, error)
 }
 
-func New() Repository {
-	conn, err := sqlx.Connect("postgres", getConnectionString())
-	assert.NoError(err, "connecting to database")
-	return Repository{db: conn}
+// New builds the repository selected by the config backend. The memory backend loads the JSON dump in the config
+// file, any other value connects to PostgreSQL.
+func New(ctx context.Context, config *Config) (Repository, error) {
+	if config.Backend == BACKEND_MEMORY {
+		repository, err := NewMemoryFromFile(config.File)
+		if err != nil {
+			return nil, err
+		}
+		return repository, nil
+	}
+
+	repository, err := NewPostgres(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return repository, nil
 }
 
-func getConnectionString() string {
-	err := godotenv.Load(".env")
-	assert.NoError(err, "loading .env file")
+type PostgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewPostgres(ctx context.Context, config *Config) (*PostgresRepository, error) {
+	connectionString, err := config.ConnectionString()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sqlx.ConnectContext(ctx, "postgres", connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to database: %w", err)
+	}
 
-	host := os.Getenv("DATABASE_HOST")
-	port := "5432"
-	user := os.Getenv("DATABASE_USERNAME")
-	password := os.Getenv("DATABASE_PASSWORD")
-	dbname := os.Getenv("DATABASE_NAME")
+	if config.PoolSize > 0 {
+		conn.SetMaxOpenConns(config.PoolSize)
+		conn.SetMaxIdleConns(config.PoolSize)
+	}
 
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=require", host, port, user, password, dbname)
+	return &PostgresRepository{db: conn}, nil
 }
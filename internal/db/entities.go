@@ -1,8 +1,10 @@
 package db
 
 import (
+	"context"
+
 	sq "github.com/Masterminds/squirrel"
-	"github.com/iagocanalejas/rstats/internal/utils/assert"
+	"github.com/lib/pq"
 )
 
 type EntityRow struct {
@@ -10,19 +12,86 @@ type EntityRow struct {
 	Name string `db:"name"`
 }
 
-func (r *Repository) GetClubByID(clubID int64) (*EntityRow, error) {
+// ClubNamesRow is a club with all the raw names its crews used in the races.
+type ClubNamesRow struct {
+	ID       int64          `db:"id"`
+	Name     string         `db:"name"`
+	RawNames pq.StringArray `db:"raw_names"`
+}
+
+func (r *PostgresRepository) GetClubByID(ctx context.Context, clubID int64) (*EntityRow, error) {
 	query, args, err := sq.
 		Select("e.id as id", "e.name as name").
 		From("entity e").
 		Where(sq.Eq{"e.id": clubID, "e.type": "CLUB"}).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
-	assert.NoError(err, "building query=%s args=%s", query, args)
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
 
 	var club EntityRow
-	if err = r.db.Get(&club, query, args...); err != nil {
-		return nil, err
+	if err = r.db.GetContext(ctx, &club, query, args...); err != nil {
+		return nil, queryError(err, "loading club=%d", clubID)
 	}
 
 	return &club, nil
 }
+
+// GetClubNames returns every club with the distinct raw names found in its participants.
+func (r *PostgresRepository) GetClubNames(ctx context.Context) ([]ClubNamesRow, error) {
+	query, args, err := sq.
+		Select("e.id as id", "e.name as name",
+			"COALESCE(array_agg(DISTINCT n.raw_name) FILTER (WHERE n.raw_name IS NOT NULL), '{}') as raw_names").
+		From("entity e").
+		LeftJoin("participant p ON p.club_id = e.id").
+		LeftJoin("LATERAL unnest(p.club_names) AS n(raw_name) ON true").
+		Where(sq.Eq{"e.type": "CLUB"}).
+		GroupBy("e.id", "e.name").
+		OrderBy("e.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+
+	var clubs []ClubNamesRow
+	if err = r.db.SelectContext(ctx, &clubs, query, args...); err != nil {
+		return nil, queryError(err, "loading club names")
+	}
+
+	return clubs, nil
+}
+
+// ClubRawNameRow is a raw name used by the participants of a club.
+type ClubRawNameRow struct {
+	RawName      string `db:"raw_name"`
+	ClubID       int64  `db:"club_id"`
+	ClubName     string `db:"club_name"`
+	Participants int    `db:"participants"`
+	Races        int    `db:"races"`
+}
+
+// GetClubRawNames returns how many participants and races of each club used each raw name.
+func (r *PostgresRepository) GetClubRawNames(ctx context.Context) ([]ClubRawNameRow, error) {
+	query, args, err := sq.
+		Select("n.raw_name as raw_name", "p.club_id as club_id", "e.name as club_name",
+			"count(*) as participants", "count(DISTINCT p.race_id) as races").
+		From("participant p").
+		Join("entity e ON e.id = p.club_id").
+		Join("LATERAL unnest(p.club_names) AS n(raw_name) ON true").
+		GroupBy("n.raw_name", "p.club_id", "e.name").
+		OrderBy("n.raw_name", "p.club_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+
+	rawNames := make([]ClubRawNameRow, 0)
+	if err = r.db.SelectContext(ctx, &rawNames, query, args...); err != nil {
+		return nil, queryError(err, "loading club raw names")
+	}
+
+	return rawNames, nil
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrQuery         = errors.New("query failed")
+)
+
+// queryError wraps a database error with the given context. Missing rows are reported as [ErrNotFound] while any
+// other failure is reported as [ErrQuery] keeping the original error in the chain.
+func queryError(err error, msg string, data ...any) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s: %w", fmt.Sprintf(msg, data...), ErrNotFound)
+	}
+	return fmt.Errorf("%s: %w: %w", fmt.Sprintf(msg, data...), ErrQuery, err)
+}
+
+// filterError reports an invalid filter value with the given context.
+func filterError(msg string, data ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidFilter, fmt.Sprintf(msg, data...))
+}
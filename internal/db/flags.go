@@ -1,8 +1,9 @@
 package db
 
 import (
+	"context"
+
 	sq "github.com/Masterminds/squirrel"
-	"github.com/iagocanalejas/rstats/internal/utils/assert"
 )
 
 type FlagRow struct {
@@ -10,19 +11,40 @@ type FlagRow struct {
 	Name string `db:"name"`
 }
 
-func (r *Repository) GetFlagByID(flagID int64) (*FlagRow, error) {
+func (r *PostgresRepository) GetFlagByID(ctx context.Context, flagID int64) (*FlagRow, error) {
 	query, args, err := sq.
 		Select("f.id as id", "f.name as name").
 		From("flag f").
 		Where(sq.Eq{"f.id": flagID}).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
-	assert.NoError(err, "building query=%s args=%s", query, args)
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
 
 	var flag FlagRow
-	if err = r.db.Get(&flag, query, args...); err != nil {
-		return nil, err
+	if err = r.db.GetContext(ctx, &flag, query, args...); err != nil {
+		return nil, queryError(err, "loading flag=%d", flagID)
 	}
 
 	return &flag, nil
 }
+
+func (r *PostgresRepository) GetFlags(ctx context.Context) ([]FlagRow, error) {
+	query, args, err := sq.
+		Select("f.id as id", "f.name as name").
+		From("flag f").
+		OrderBy("f.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+
+	var flags []FlagRow
+	if err = r.db.SelectContext(ctx, &flags, query, args...); err != nil {
+		return nil, queryError(err, "loading flags")
+	}
+
+	return flags, nil
+}
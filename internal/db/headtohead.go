@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"github.com/jackc/pgx/pgtype"
+)
+
+// HeadToHeadRow is a race where both clubs took part, times are in seconds.
+type HeadToHeadRow struct {
+	RaceID int64       `db:"race_id"`
+	Date   pgtype.Date `db:"date"`
+
+	ClubParticipantID  int64   `db:"club_participant_id"`
+	ClubTime           float64 `db:"club_time"`
+	RivalParticipantID int64   `db:"rival_participant_id"`
+	RivalTime          float64 `db:"rival_time"`
+}
+
+type GetHeadToHeadParams struct {
+	ClubID          int64
+	RivalID         int64
+	LeagueID        int64
+	FlagID          int64
+	Gender          string
+	Category        string
+	Day             int16
+	OnlyLeagueRaces bool
+	ApplyPenalties  bool // add the time penalties to the participant times
+}
+
+const (
+	// time of a participant in seconds from the last lap
+	timeExpression = "extract(EPOCH FROM p.laps[cardinality(p.laps)])"
+
+	// same as timeExpression adding the time penalties of the participant
+	penalizedTimeExpression = `(
+		extract(EPOCH FROM p.laps[cardinality(p.laps)])
+		+ COALESCE((SELECT SUM(pe.penalty) FROM penalty pe WHERE pe.participant_id = p.id), 0)
+	)`
+)
+
+// GetHeadToHead retrieves the races where both clubs took part using the same filters as the speeds queries.
+//  1. Selects the fastest crew of each club in every race matching the filters.
+//  2. Joins the crews of both clubs on the race.
+func (r *PostgresRepository) GetHeadToHead(ctx context.Context, params *GetHeadToHeadParams) ([]HeadToHeadRow, error) {
+	if params.ClubID <= 0 || params.RivalID <= 0 || params.ClubID == params.RivalID {
+		return nil, filterError("invalid clubs club=%d rival=%d", params.ClubID, params.RivalID)
+	}
+
+	filters, err := getSpeedFilters(
+		0, params.LeagueID, params.FlagID,
+		params.Gender, params.Category,
+		params.Day,
+		BRANCH_ALL, params.OnlyLeagueRaces,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	crewsQuery := sq.
+		Select("DISTINCT ON (p.race_id, p.club_id) p.id", "p.race_id", "p.club_id", "r.date").
+		Column(fmt.Sprintf("CAST(%s AS DOUBLE PRECISION) as time", timeColumn(params.ApplyPenalties))).
+		From("participant p").
+		Join("race r ON p.race_id = r.id").
+		Where(filters).
+		Where(sq.Eq{"p.club_id": []int64{params.ClubID, params.RivalID}}).
+		OrderBy("p.race_id", "p.club_id", "time")
+
+	query, args, err := sq.
+		Select("c.race_id", "c.date",
+			"c.id as club_participant_id", "c.time as club_time",
+			"v.id as rival_participant_id", "v.time as rival_time").
+		PrefixExpr(sq.Expr("WITH crews AS (?)", crewsQuery)).
+		From("crews c").
+		Join("crews v ON v.race_id = c.race_id").
+		Where(sq.Eq{"c.club_id": params.ClubID}).
+		Where(sq.Eq{"v.club_id": params.RivalID}).
+		OrderBy("c.date", "c.race_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+
+	prettylog.Debug("%s %v", query, args)
+
+	meetings := make([]HeadToHeadRow, 0)
+	if err = r.db.SelectContext(ctx, &meetings, query, args...); err != nil {
+		return nil, queryError(err, "loading head to head params=%v", *params)
+	}
+
+	return meetings, nil
+}
+
+func timeColumn(applyPenalties bool) string {
+	if applyPenalties {
+		return penalizedTimeExpression
+	}
+	return timeExpression
+}
@@ -1,8 +1,9 @@
 package db
 
 import (
+	"context"
+
 	sq "github.com/Masterminds/squirrel"
-	"github.com/iagocanalejas/rstats/internal/utils/assert"
 )
 
 type LeagueRow struct {
@@ -13,19 +14,40 @@ type LeagueRow struct {
 	Category *string `db:"category"`
 }
 
-func (r *Repository) GetLeagueByID(leagueID int64) (*LeagueRow, error) {
+func (r *PostgresRepository) GetLeagueByID(ctx context.Context, leagueID int64) (*LeagueRow, error) {
 	query, args, err := sq.
 		Select("l.id as id", "l.name as name", "l.gender as gender", "l.category as category", "l.symbol as symbol").
 		From("league l").
 		Where(sq.Eq{"l.id": leagueID}).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
-	assert.NoError(err, "building query=%s args=%s", query, args)
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
 
 	var league LeagueRow
-	if err = r.db.Get(&league, query, args...); err != nil {
-		return nil, err
+	if err = r.db.GetContext(ctx, &league, query, args...); err != nil {
+		return nil, queryError(err, "loading league=%d", leagueID)
 	}
 
 	return &league, nil
 }
+
+func (r *PostgresRepository) GetLeagues(ctx context.Context) ([]LeagueRow, error) {
+	query, args, err := sq.
+		Select("l.id as id", "l.name as name", "l.gender as gender", "l.category as category", "l.symbol as symbol").
+		From("league l").
+		OrderBy("l.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+
+	var leagues []LeagueRow
+	if err = r.db.SelectContext(ctx, &leagues, query, args...); err != nil {
+		return nil, queryError(err, "loading leagues")
+	}
+
+	return leagues, nil
+}
@@ -0,0 +1,1256 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iagocanalejas/rstats/internal/utils/arrays"
+	utils "github.com/iagocanalejas/rstats/internal/utils/strings"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/lib/pq"
+)
+
+// MemoryData is the JSON layout loaded by the memory backend. Each collection mirrors the columns of the PostgreSQL
+// table with the same name, so a dump can be generated with a `json_agg` over each table.
+type MemoryData struct {
+	Races        []MemoryRace        `json:"races"`
+	Participants []MemoryParticipant `json:"participants"`
+	Penalties    []MemoryPenalty     `json:"penalties"`
+	Entities     []MemoryEntity      `json:"entities"`
+	Leagues      []MemoryLeague      `json:"leagues"`
+	Flags        []MemoryFlag        `json:"flags"`
+	Trophies     []MemoryTrophy      `json:"trophies"`
+}
+
+type MemoryRace struct {
+	ID int64 `json:"id"`
+
+	TrophyID      *int64 `json:"trophy_id"`
+	TrophyEdition *int16 `json:"trophy_edition"`
+	FlagID        *int64 `json:"flag_id"`
+	FlagEdition   *int16 `json:"flag_edition"`
+	LeagueID      *int64 `json:"league_id"`
+	AssociatedID  *int64 `json:"associated_id"`
+
+	Day  int16  `json:"day"`
+	Date string `json:"date"` // YYYY-MM-DD
+
+	Gender   string `json:"gender"`
+	Category string `json:"category"`
+	Type     string `json:"type"`
+	Modality string `json:"modality"`
+
+	Laps        *int16 `json:"laps"`
+	Lanes       *int16 `json:"lanes"`
+	IsCancelled bool   `json:"cancelled"`
+
+	Sponsor  *string         `json:"sponsor"`
+	Metadata json.RawMessage `json:"metadata"`
+
+	date time.Time
+}
+
+type MemoryParticipant struct {
+	ID     int64 `json:"id"`
+	RaceID int64 `json:"race_id"`
+
+	ClubID    int64    `json:"club_id"`
+	ClubNames []string `json:"club_names"`
+
+	Gender   string `json:"gender"`
+	Category string `json:"category"`
+	Distance *int   `json:"distance"`
+
+	Laps   []string `json:"laps"`
+	Lane   *int16   `json:"lane"`
+	Series *int16   `json:"series"`
+
+	IsRetired bool `json:"retired"`
+	IsGuest   bool `json:"guest"`
+	IsAbsent  bool `json:"absent"`
+
+	time    time.Duration // time of the last lap, zero when the participant has no laps
+	hasTime bool
+}
+
+type MemoryPenalty struct {
+	ID               int64   `json:"id"`
+	ParticipantID    int64   `json:"participant_id"`
+	Penalty          int     `json:"penalty"`
+	Disqualification bool    `json:"disqualification"`
+	Reason           *string `json:"reason"`
+}
+
+type MemoryEntity struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type MemoryLeague struct {
+	ID       int64   `json:"id"`
+	Name     string  `json:"name"`
+	Symbol   string  `json:"symbol"`
+	Gender   *string `json:"gender"`
+	Category *string `json:"category"`
+}
+
+type MemoryFlag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type MemoryTrophy struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// MemoryRepository is a [Repository] that keeps the whole dataset in memory. It is meant for offline analysis and
+// for running the service without a live database, so it favours simplicity over performance.
+type MemoryRepository struct {
+	data *MemoryData
+
+	races        map[int64]*MemoryRace
+	entities     map[int64]*MemoryEntity
+	leagues      map[int64]*MemoryLeague
+	flags        map[int64]*MemoryFlag
+	trophies     map[int64]*MemoryTrophy
+	disqualified map[int64]bool          // participant IDs with a disqualification penalty
+	penaltyTime  map[int64]time.Duration // total time penalty of each participant
+}
+
+func NewMemoryFromFile(path string) (*MemoryRepository, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading memory database file=%s: %w", path, err)
+	}
+
+	var data MemoryData
+	if err = json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("parsing memory database file=%s: %w", path, err)
+	}
+
+	return NewMemory(&data)
+}
+
+func NewMemory(data *MemoryData) (*MemoryRepository, error) {
+	m := &MemoryRepository{
+		data:         data,
+		races:        make(map[int64]*MemoryRace, len(data.Races)),
+		entities:     make(map[int64]*MemoryEntity, len(data.Entities)),
+		leagues:      make(map[int64]*MemoryLeague, len(data.Leagues)),
+		flags:        make(map[int64]*MemoryFlag, len(data.Flags)),
+		trophies:     make(map[int64]*MemoryTrophy, len(data.Trophies)),
+		disqualified: make(map[int64]bool),
+		penaltyTime:  make(map[int64]time.Duration),
+	}
+
+	for i := range data.Races {
+		race := &data.Races[i]
+		date, err := time.Parse(time.DateOnly, race.Date)
+		if err != nil {
+			return nil, fmt.Errorf("parsing date for race=%d: %w", race.ID, err)
+		}
+		race.date = date
+		m.races[race.ID] = race
+	}
+	for i := range data.Participants {
+		participant := &data.Participants[i]
+		if len(participant.Laps) > 0 {
+			lastLap, err := utils.ParseInterval(participant.Laps[len(participant.Laps)-1])
+			if err != nil {
+				return nil, fmt.Errorf("parsing laps for participant=%d: %w", participant.ID, err)
+			}
+			participant.time, participant.hasTime = lastLap, true
+		}
+	}
+	for i := range data.Entities {
+		m.entities[data.Entities[i].ID] = &data.Entities[i]
+	}
+	for i := range data.Leagues {
+		m.leagues[data.Leagues[i].ID] = &data.Leagues[i]
+	}
+	for i := range data.Flags {
+		m.flags[data.Flags[i].ID] = &data.Flags[i]
+	}
+	for i := range data.Trophies {
+		m.trophies[data.Trophies[i].ID] = &data.Trophies[i]
+	}
+	for _, penalty := range data.Penalties {
+		if penalty.Disqualification {
+			m.disqualified[penalty.ParticipantID] = true
+		}
+		m.penaltyTime[penalty.ParticipantID] += time.Duration(penalty.Penalty) * time.Second
+	}
+
+	return m, nil
+}
+
+func (m *MemoryRepository) GetClubByID(ctx context.Context, clubID int64) (*EntityRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entity, ok := m.entities[clubID]
+	if !ok || entity.Type != "CLUB" {
+		return nil, queryError(sql.ErrNoRows, "loading club=%d", clubID)
+	}
+	return &EntityRow{ID: entity.ID, Name: entity.Name}, nil
+}
+
+func (m *MemoryRepository) GetClubNames(ctx context.Context) ([]ClubNamesRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rawNames := make(map[int64]map[string]bool)
+	for _, participant := range m.data.Participants {
+		for _, name := range participant.ClubNames {
+			if rawNames[participant.ClubID] == nil {
+				rawNames[participant.ClubID] = make(map[string]bool)
+			}
+			rawNames[participant.ClubID][name] = true
+		}
+	}
+
+	clubs := make([]ClubNamesRow, 0)
+	for _, entity := range m.data.Entities {
+		if entity.Type != "CLUB" {
+			continue
+		}
+		names := make(pq.StringArray, 0, len(rawNames[entity.ID]))
+		for name := range rawNames[entity.ID] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		clubs = append(clubs, ClubNamesRow{ID: entity.ID, Name: entity.Name, RawNames: names})
+	}
+	sort.Slice(clubs, func(i, j int) bool { return clubs[i].ID < clubs[j].ID })
+	return clubs, nil
+}
+
+func (m *MemoryRepository) GetClubRawNames(ctx context.Context) ([]ClubRawNameRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type usageKey struct {
+		rawName string
+		clubID  int64
+	}
+
+	usages := make(map[usageKey]*ClubRawNameRow)
+	races := make(map[usageKey]map[int64]bool)
+	for _, participant := range m.data.Participants {
+		club, ok := m.entities[participant.ClubID]
+		if !ok {
+			continue
+		}
+		for _, name := range participant.ClubNames {
+			key := usageKey{rawName: name, clubID: participant.ClubID}
+			if usages[key] == nil {
+				usages[key] = &ClubRawNameRow{RawName: name, ClubID: club.ID, ClubName: club.Name}
+				races[key] = make(map[int64]bool)
+			}
+			usages[key].Participants++
+			races[key][participant.RaceID] = true
+		}
+	}
+
+	rawNames := make([]ClubRawNameRow, 0, len(usages))
+	for key, usage := range usages {
+		usage.Races = len(races[key])
+		rawNames = append(rawNames, *usage)
+	}
+
+	// ORDER BY n.raw_name, p.club_id
+	sort.Slice(rawNames, func(i, j int) bool {
+		if rawNames[i].RawName != rawNames[j].RawName {
+			return rawNames[i].RawName < rawNames[j].RawName
+		}
+		return rawNames[i].ClubID < rawNames[j].ClubID
+	})
+	return rawNames, nil
+}
+
+func (m *MemoryRepository) GetFlagByID(ctx context.Context, flagID int64) (*FlagRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	flag, ok := m.flags[flagID]
+	if !ok {
+		return nil, queryError(sql.ErrNoRows, "loading flag=%d", flagID)
+	}
+	return &FlagRow{ID: flag.ID, Name: flag.Name}, nil
+}
+
+func (m *MemoryRepository) GetFlags(ctx context.Context) ([]FlagRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	flags := make([]FlagRow, len(m.data.Flags))
+	for idx, flag := range m.data.Flags {
+		flags[idx] = FlagRow{ID: flag.ID, Name: flag.Name}
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].ID < flags[j].ID })
+	return flags, nil
+}
+
+func (m *MemoryRepository) GetLeagueByID(ctx context.Context, leagueID int64) (*LeagueRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	league, ok := m.leagues[leagueID]
+	if !ok {
+		return nil, queryError(sql.ErrNoRows, "loading league=%d", leagueID)
+	}
+	return &LeagueRow{ID: league.ID, Name: league.Name, Symbol: league.Symbol, Gender: league.Gender, Category: league.Category}, nil
+}
+
+func (m *MemoryRepository) GetLeagues(ctx context.Context) ([]LeagueRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	leagues := make([]LeagueRow, len(m.data.Leagues))
+	for idx, league := range m.data.Leagues {
+		leagues[idx] = LeagueRow{ID: league.ID, Name: league.Name, Symbol: league.Symbol, Gender: league.Gender, Category: league.Category}
+	}
+	sort.Slice(leagues, func(i, j int) bool { return leagues[i].ID < leagues[j].ID })
+	return leagues, nil
+}
+
+func (m *MemoryRepository) GetTrophyByID(ctx context.Context, trophyID int64) (*TrophyRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	trophy, ok := m.trophies[trophyID]
+	if !ok {
+		return nil, queryError(sql.ErrNoRows, "loading trophy=%d", trophyID)
+	}
+	return &TrophyRow{ID: trophy.ID, Name: trophy.Name}, nil
+}
+
+func (m *MemoryRepository) GetRaceByID(ctx context.Context, raceID int64) (*RaceRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	race, ok := m.races[raceID]
+	if !ok {
+		return nil, queryError(sql.ErrNoRows, "loading race=%d", raceID)
+	}
+	row := m.raceRow(race)
+	return &row, nil
+}
+
+func (m *MemoryRepository) SearchRaces(ctx context.Context, filters *SearchRaceParams) ([]RaceRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ranked := filters.Ranked && filters.Keywords != ""
+	if ranked && filters.After != nil && filters.After.Score == nil {
+		return nil, filterError("ranked search cursor without score")
+	}
+
+	races := make([]*MemoryRace, 0)
+	matches := make(map[int64]*raceMatch)
+	for i := range m.data.Races {
+		race := &m.data.Races[i]
+		if !m.matchesSearch(race, filters) {
+			continue
+		}
+		if ranked {
+			match := m.bestMatch(race, filters.Keywords)
+			if match == nil || match.score < MIN_MATCH_SCORE {
+				continue
+			}
+			matches[race.ID] = match
+		}
+		races = append(races, race)
+	}
+
+	compare := func(race *MemoryRace, score *float64, date time.Time, leagueID *int64, id int64) int {
+		if ranked {
+			return compareRankedRaces(matches[race.ID].score, race.date, race.ID, *score, date, id)
+		}
+		return compareRaces(race.date, race.LeagueID, race.ID, date, leagueID, id)
+	}
+
+	sort.Slice(races, func(i, j int) bool {
+		var score *float64
+		if ranked {
+			score = &matches[races[j].ID].score
+		}
+		return compare(races[i], score, races[j].date, races[j].LeagueID, races[j].ID) < 0
+	})
+
+	if filters.After != nil {
+		after := filters.After
+		start := sort.Search(len(races), func(i int) bool {
+			return compare(races[i], after.Score, after.Date, after.LeagueID, after.ID) > 0
+		})
+		races = races[start:]
+	}
+
+	if filters.Limit > 0 && uint64(len(races)) > filters.Limit {
+		races = races[:filters.Limit]
+	}
+
+	rows := make([]RaceRow, len(races))
+	for idx, race := range races {
+		rows[idx] = m.raceRow(race)
+		if match, ok := matches[race.ID]; ok {
+			rows[idx].MatchField = &match.field
+			rows[idx].MatchValue = &match.value
+			rows[idx].MatchScore = &match.score
+		}
+	}
+	return rows, nil
+}
+
+// compareRankedRaces compares two races in the ranked search order: score DESC, date DESC, id.
+func compareRankedRaces(scoreA float64, dateA time.Time, idA int64, scoreB float64, dateB time.Time, idB int64) int {
+	if scoreA != scoreB {
+		if scoreA > scoreB {
+			return -1
+		}
+		return 1
+	}
+	if !dateA.Equal(dateB) {
+		if dateA.After(dateB) {
+			return -1
+		}
+		return 1
+	}
+	if idA != idB {
+		if idA < idB {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+type raceMatch struct {
+	field string
+	value string
+	score float64
+}
+
+// bestMatch mirrors the ranked search of PostgreSQL, returning the race name that best matches the keywords. Ties are
+// broken by field priority (trophy, flag, league, sponsor and clubs) and then by value.
+func (m *MemoryRepository) bestMatch(race *MemoryRace, keywords string) *raceMatch {
+	candidates := make([]raceMatch, 0)
+	if trophy := m.trophy(race.TrophyID); trophy != nil {
+		candidates = append(candidates, raceMatch{field: MATCH_TROPHY, value: trophy.Name})
+	}
+	if flag := m.flag(race.FlagID); flag != nil {
+		candidates = append(candidates, raceMatch{field: MATCH_FLAG, value: flag.Name})
+	}
+	if league := m.league(race.LeagueID); league != nil {
+		candidates = append(candidates, raceMatch{field: MATCH_LEAGUE, value: league.Name})
+	}
+	if race.Sponsor != nil {
+		candidates = append(candidates, raceMatch{field: MATCH_SPONSOR, value: *race.Sponsor})
+	}
+	clubs := make([]raceMatch, 0)
+	for _, participant := range m.participantsOf(race.ID) {
+		if entity, ok := m.entities[participant.ClubID]; ok {
+			clubs = append(clubs, raceMatch{field: MATCH_CLUB, value: entity.Name})
+		}
+	}
+	sort.Slice(clubs, func(i, j int) bool { return clubs[i].value < clubs[j].value })
+	candidates = append(candidates, clubs...)
+
+	var best *raceMatch
+	for i := range candidates {
+		candidates[i].score = utils.WordSimilarity(keywords, candidates[i].value)
+		if best == nil || candidates[i].score > best.score {
+			best = &candidates[i]
+		}
+	}
+	return best
+}
+
+// compareRaces compares two races in the search order: date DESC, league_id, id. PostgreSQL sorts NULLs last on
+// ascending orders, so races without a league go after the others.
+func compareRaces(dateA time.Time, leagueA *int64, idA int64, dateB time.Time, leagueB *int64, idB int64) int {
+	if !dateA.Equal(dateB) {
+		if dateA.After(dateB) {
+			return -1
+		}
+		return 1
+	}
+	if (leagueA == nil) != (leagueB == nil) {
+		if leagueA != nil {
+			return -1
+		}
+		return 1
+	}
+	if leagueA != nil && *leagueA != *leagueB {
+		if *leagueA < *leagueB {
+			return -1
+		}
+		return 1
+	}
+	if idA != idB {
+		if idA < idB {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func (m *MemoryRepository) matchesSearch(race *MemoryRace, filters *SearchRaceParams) bool {
+	trophyName, flagName, sponsor := "", "", ""
+	if trophy := m.trophy(race.TrophyID); trophy != nil {
+		trophyName = trophy.Name
+	}
+	if flag := m.flag(race.FlagID); flag != nil {
+		flagName = flag.Name
+	}
+	if race.Sponsor != nil {
+		sponsor = *race.Sponsor
+	}
+
+	if filters.Keywords != "" && !filters.Ranked &&
+		!containsFold(trophyName, filters.Keywords) &&
+		!containsFold(flagName, filters.Keywords) &&
+		!containsFold(sponsor, filters.Keywords) {
+		return false
+	}
+	if filters.Year > 0 && race.date.Year() != int(filters.Year) {
+		return false
+	}
+	if filters.Trophy != "" && !containsFold(trophyName, filters.Trophy) {
+		return false
+	}
+	if filters.TrophyID > 0 && (race.TrophyID == nil || *race.TrophyID != filters.TrophyID) {
+		return false
+	}
+	if filters.Flag != "" && !containsFold(flagName, filters.Flag) {
+		return false
+	}
+	if filters.FlagID > 0 && (race.FlagID == nil || *race.FlagID != filters.FlagID) {
+		return false
+	}
+	if filters.League != "" {
+		league := m.league(race.LeagueID)
+		if league == nil || (!containsFold(league.Name, filters.League) && !containsFold(league.Symbol, filters.League)) {
+			return false
+		}
+	}
+	if filters.LeagueID > 0 && (race.LeagueID == nil || *race.LeagueID != filters.LeagueID) {
+		return false
+	}
+	if filters.Participant != "" || filters.ParticipantID > 0 {
+		matchesName, matchesID := filters.Participant == "", filters.ParticipantID <= 0
+		for _, participant := range m.participantsOf(race.ID) {
+			if entity, ok := m.entities[participant.ClubID]; ok && containsFold(entity.Name, filters.Participant) {
+				matchesName = true
+			}
+			if participant.ClubID == filters.ParticipantID {
+				matchesID = true
+			}
+		}
+		if !matchesName || !matchesID {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *MemoryRepository) GetParticipantsByRaceID(ctx context.Context, raceID int64) ([]ParticipantRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	participants := m.participantsOf(raceID)
+
+	// ORDER BY disqualified, p.laps[ARRAY_UPPER(p.laps, 1)] ASC, participants without laps go last
+	sort.SliceStable(participants, func(i, j int) bool {
+		if m.disqualified[participants[i].ID] != m.disqualified[participants[j].ID] {
+			return !m.disqualified[participants[i].ID]
+		}
+		if !participants[i].hasTime || !participants[j].hasTime {
+			return participants[i].hasTime && !participants[j].hasTime
+		}
+		return participants[i].time < participants[j].time
+	})
+
+	rows := make([]ParticipantRow, len(participants))
+	for idx, participant := range participants {
+		rows[idx] = m.participantRow(participant)
+	}
+	return rows, nil
+}
+
+func (m *MemoryRepository) GetParticipantLapsBy(ctx context.Context, params *GetParticipantLapsByParams) ([]ParticipantLapsRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := m.speedsQuery(
+		params.ClubID, params.LeagueID, 0,
+		params.Gender, params.Category,
+		0, params.Year,
+		BRANCH_ALL, false, false,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	participants := make([]ParticipantLapsRow, 0)
+	for _, entry := range entries {
+		r, p := entry.race, entry.participant
+		if r.Laps == nil || len(p.Laps) != int(*r.Laps) {
+			continue
+		}
+		participants = append(participants, ParticipantLapsRow{
+			ID:       p.ID,
+			RaceID:   r.ID,
+			Date:     pgtype.Date{Time: r.date, Status: pgtype.Present},
+			RaceLaps: *r.Laps,
+			Distance: *p.Distance,
+			Laps:     p.Laps,
+		})
+	}
+
+	// ORDER BY r.date, p.id
+	sort.SliceStable(participants, func(i, j int) bool {
+		if !participants[i].Date.Time.Equal(participants[j].Date.Time) {
+			return participants[i].Date.Time.Before(participants[j].Date.Time)
+		}
+		return participants[i].ID < participants[j].ID
+	})
+	return participants, nil
+}
+
+func (m *MemoryRepository) GetHeadToHead(ctx context.Context, params *GetHeadToHeadParams) ([]HeadToHeadRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if params.ClubID <= 0 || params.RivalID <= 0 || params.ClubID == params.RivalID {
+		return nil, filterError("invalid clubs club=%d rival=%d", params.ClubID, params.RivalID)
+	}
+
+	entries, err := m.speedsQuery(
+		0, params.LeagueID, params.FlagID,
+		params.Gender, params.Category,
+		params.Day, 0,
+		BRANCH_ALL, params.OnlyLeagueRaces, params.ApplyPenalties,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// DISTINCT ON (p.race_id, p.club_id) ... ORDER BY time
+	type crew struct {
+		id   int64
+		time float64
+	}
+	crews := make(map[[2]int64]crew)
+	races := make([]*MemoryRace, 0)
+	for _, entry := range entries {
+		p := entry.participant
+		if p.ClubID != params.ClubID && p.ClubID != params.RivalID {
+			continue
+		}
+
+		t := p.time
+		if params.ApplyPenalties {
+			t += m.penaltyTime[p.ID]
+		}
+		key := [2]int64{entry.race.ID, p.ClubID}
+		current, ok := crews[key]
+		if !ok && p.ClubID == params.ClubID {
+			races = append(races, entry.race)
+		}
+		if !ok || t.Seconds() < current.time {
+			crews[key] = crew{id: p.ID, time: t.Seconds()}
+		}
+	}
+
+	meetings := make([]HeadToHeadRow, 0)
+	for _, r := range races {
+		club := crews[[2]int64{r.ID, params.ClubID}]
+		rival, ok := crews[[2]int64{r.ID, params.RivalID}]
+		if !ok {
+			continue
+		}
+		meetings = append(meetings, HeadToHeadRow{
+			RaceID:             r.ID,
+			Date:               pgtype.Date{Time: r.date, Status: pgtype.Present},
+			ClubParticipantID:  club.id,
+			ClubTime:           club.time,
+			RivalParticipantID: rival.id,
+			RivalTime:          rival.time,
+		})
+	}
+
+	// ORDER BY c.date, c.race_id
+	sort.SliceStable(meetings, func(i, j int) bool {
+		if !meetings[i].Date.Time.Equal(meetings[j].Date.Time) {
+			return meetings[i].Date.Time.Before(meetings[j].Date.Time)
+		}
+		return meetings[i].RaceID < meetings[j].RaceID
+	})
+	return meetings, nil
+}
+
+func (m *MemoryRepository) GetAuditParticipants(ctx context.Context) ([]AuditParticipantRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	participants := make([]AuditParticipantRow, 0)
+	for i := range m.data.Participants {
+		p := &m.data.Participants[i]
+		r, ok := m.races[p.RaceID]
+		if !ok || r.IsCancelled {
+			continue
+		}
+
+		participants = append(participants, AuditParticipantRow{
+			ID:        p.ID,
+			RaceID:    p.RaceID,
+			ClubID:    p.ClubID,
+			ClubNames: append(pq.StringArray{}, p.ClubNames...),
+			Gender:    p.Gender,
+			Category:  p.Category,
+			Distance:  p.Distance,
+			Laps:      append(pq.StringArray{}, p.Laps...),
+			Lane:      p.Lane,
+			Series:    p.Series,
+			IsRetired: p.IsRetired,
+			IsGuest:   p.IsGuest,
+			IsAbsent:  p.IsAbsent,
+			RaceLaps:  r.Laps,
+			RaceLanes: r.Lanes,
+		})
+	}
+
+	// ORDER BY p.race_id, p.id
+	sort.SliceStable(participants, func(i, j int) bool {
+		if participants[i].RaceID != participants[j].RaceID {
+			return participants[i].RaceID < participants[j].RaceID
+		}
+		return participants[i].ID < participants[j].ID
+	})
+	return participants, nil
+}
+
+func (m *MemoryRepository) GetEmptyRaceIDs(ctx context.Context) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	raceIDs := make([]int64, 0)
+	for _, race := range m.data.Races {
+		if !race.IsCancelled && len(m.participantsOf(race.ID)) == 0 {
+			raceIDs = append(raceIDs, race.ID)
+		}
+	}
+	sort.Slice(raceIDs, func(i, j int) bool { return raceIDs[i] < raceIDs[j] })
+	return raceIDs, nil
+}
+
+func (m *MemoryRepository) GetPenaltiesByRaceID(ctx context.Context, raceID int64) ([]PenaltyRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	participants := make(map[int64]bool)
+	for _, participant := range m.participantsOf(raceID) {
+		participants[participant.ID] = true
+	}
+
+	penalties := make([]PenaltyRow, 0)
+	for _, penalty := range m.data.Penalties {
+		if participants[penalty.ParticipantID] {
+			penalties = append(penalties, PenaltyRow{
+				ID:               penalty.ID,
+				ParticipantID:    penalty.ParticipantID,
+				Penalty:          penalty.Penalty,
+				Disqualification: penalty.Disqualification,
+				Reason:           penalty.Reason,
+			})
+		}
+	}
+	sort.Slice(penalties, func(i, j int) bool {
+		if penalties[i].ParticipantID != penalties[j].ParticipantID {
+			return penalties[i].ParticipantID < penalties[j].ParticipantID
+		}
+		return penalties[i].ID < penalties[j].ID
+	})
+	return penalties, nil
+}
+
+func (m *MemoryRepository) GetParticipantsWithSpeed(ctx context.Context, category string) ([]ParticipantRowWithSpeed, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	participants := make([]ParticipantRowWithSpeed, 0)
+	for i := range m.data.Participants {
+		participant := &m.data.Participants[i]
+		race, ok := m.races[participant.RaceID]
+		if !ok ||
+			race.IsCancelled ||
+			participant.IsRetired ||
+			participant.IsGuest ||
+			(category != "" && participant.Category != category) ||
+			!hasSpeed(participant) ||
+			m.disqualified[participant.ID] {
+			continue
+		}
+
+		row := m.participantRow(participant)
+		participants = append(participants, ParticipantRowWithSpeed{
+			ID:             row.ID,
+			RaceID:         row.RaceID,
+			Gender:         row.Gender,
+			Category:       row.Category,
+			Distance:       row.Distance,
+			ClubId:         row.ClubId,
+			ClubName:       row.ClubName,
+			ClubRawNames:   row.ClubRawNames,
+			IsDisqualified: row.IsDisqualified,
+			Laps:           row.Laps,
+			Lane:           row.Lane,
+			Series:         row.Series,
+			Speed:          speedOf(participant),
+			Date:           pgtype.Date{Time: race.date, Status: pgtype.Present},
+		})
+	}
+
+	// ORDER BY r.date, p.race_id, p.gender, p.category
+	sort.SliceStable(participants, func(i, j int) bool {
+		a, b := participants[i], participants[j]
+		if !a.Date.Time.Equal(b.Date.Time) {
+			return a.Date.Time.Before(b.Date.Time)
+		}
+		if a.RaceID != b.RaceID {
+			return a.RaceID < b.RaceID
+		}
+		if a.Gender != b.Gender {
+			return a.Gender < b.Gender
+		}
+		return a.Category < b.Category
+	})
+
+	return participants, nil
+}
+
+func (m *MemoryRepository) GetYearSpeedsBy(ctx context.Context, params *GetYearSpeedsByParams) ([]int, *map[int][]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := m.speedsQuery(
+		params.ClubID, params.LeagueID, params.FlagID,
+		params.Gender, params.Category,
+		params.Day, 0,
+		params.Branch, params.OnlyLeagueRaces, params.ApplyPenalties,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if params.Normalize {
+		entries = normalizeSpeeds(excludeParticipants(entries, params.Excluded))
+	}
+
+	years := make([]int, 0)
+	speeds := make(map[int][]float64)
+	for _, entry := range entries {
+		year := entry.race.date.Year()
+		if len(params.Years) > 0 && !arrays.Contains(params.Years, year) {
+			continue
+		}
+		if _, ok := speeds[year]; !ok {
+			years = append(years, year)
+		}
+		speeds[year] = append(speeds[year], entry.speed)
+	}
+	sort.Ints(years)
+
+	return years, &speeds, nil
+}
+
+func (m *MemoryRepository) GetDatedSpeedsBy(ctx context.Context, params *GetYearSpeedsByParams) ([]DatedSpeedRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := m.speedsQuery(
+		params.ClubID, params.LeagueID, params.FlagID,
+		params.Gender, params.Category,
+		params.Day, 0,
+		params.Branch, params.OnlyLeagueRaces, params.ApplyPenalties,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if params.Normalize {
+		entries = normalizeSpeeds(excludeParticipants(entries, params.Excluded))
+	}
+
+	speeds := make([]DatedSpeedRow, 0, len(entries))
+	for _, entry := range entries {
+		if len(params.Years) > 0 && !arrays.Contains(params.Years, entry.race.date.Year()) {
+			continue
+		}
+		speeds = append(speeds, DatedSpeedRow{
+			ParticipantID: entry.participant.ID,
+			RaceID:        entry.race.ID,
+			ClubID:        entry.participant.ClubID,
+			Date:          pgtype.Date{Time: entry.race.date, Status: pgtype.Present},
+			Speed:         entry.speed,
+		})
+	}
+
+	// ORDER BY date, race_id, speed DESC
+	sort.SliceStable(speeds, func(i, j int) bool {
+		if !speeds[i].Date.Time.Equal(speeds[j].Date.Time) {
+			return speeds[i].Date.Time.Before(speeds[j].Date.Time)
+		}
+		if speeds[i].RaceID != speeds[j].RaceID {
+			return speeds[i].RaceID < speeds[j].RaceID
+		}
+		return speeds[i].Speed > speeds[j].Speed
+	})
+	return speeds, nil
+}
+
+func (m *MemoryRepository) GetNthSpeedsBy(ctx context.Context, params *GetNthSpeedsByParams) ([]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if params.Index <= 0 {
+		return nil, filterError("no index provided %v", *params)
+	}
+	if params.Year <= 0 {
+		return nil, filterError("no year provided %v", *params)
+	}
+
+	entries, err := m.speedsQuery(
+		params.ClubID, params.LeagueID, 0,
+		params.Gender, params.Category,
+		params.Day, params.Year,
+		params.Branch, params.OnlyLeagueRaces, params.ApplyPenalties,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if params.Normalize {
+		entries = normalizeSpeeds(excludeParticipants(entries, params.Excluded))
+	}
+
+	raceIDs := make([]int64, 0)
+	raceSpeeds := make(map[int64][]float64)
+	for _, entry := range entries {
+		if _, ok := raceSpeeds[entry.race.ID]; !ok {
+			raceIDs = append(raceIDs, entry.race.ID)
+		}
+		raceSpeeds[entry.race.ID] = append(raceSpeeds[entry.race.ID], entry.speed)
+	}
+
+	speeds := make([]float64, 0)
+	for _, raceID := range raceIDs {
+		values := raceSpeeds[raceID]
+		if len(values) < params.Index {
+			continue
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(values)))
+		speeds = append(speeds, values[params.Index-1])
+	}
+
+	return speeds, nil
+}
+
+// sponsorSuffix matches the sponsor of a raw club name, which goes after a " - " or in parentheses.
+var sponsorSuffix = regexp.MustCompile(` (- |\().*$`)
+
+// crewBranch is the branch letter of a raw club name, empty for the main crews. Mirrors the branchClause of the speed
+// filters.
+func crewBranch(name string) string {
+	name = sponsorSuffix.ReplaceAllString(name, "")
+	if len(name) > 2 && name[len(name)-2] == ' ' && strings.ContainsRune(BRANCH_LETTERS, rune(name[len(name)-1])) {
+		return name[len(name)-1:]
+	}
+	return ""
+}
+
+type speedEntry struct {
+	race        *MemoryRace
+	participant *MemoryParticipant
+	speed       float64
+}
+
+// speedsQuery is the in-memory counterpart of the `speeds_query` CTE, it returns the speeds of all the participants
+// matching the filters ordered by race date and speed.
+func (m *MemoryRepository) speedsQuery(
+	clubID, leagueID, flagID int64,
+	gender, category string,
+	day, year int16,
+	branch string,
+	onlyLeagueRaces, applyPenalties bool,
+) ([]speedEntry, error) {
+	if err := validateSpeedFilters(gender, category, day, branch); err != nil {
+		return nil, err
+	}
+
+	strictFilters := onlyLeagueRaces || leagueID > 0
+	entries := make([]speedEntry, 0)
+	for i := range m.data.Participants {
+		p := &m.data.Participants[i]
+		r, ok := m.races[p.RaceID]
+		if !ok ||
+			r.IsCancelled ||
+			p.IsRetired ||
+			p.IsGuest ||
+			p.IsAbsent ||
+			!hasSpeed(p) ||
+			m.disqualified[p.ID] {
+			continue
+		}
+
+		if p.Gender != gender || (r.Gender != gender && (strictFilters || r.Gender != "ALL")) {
+			continue
+		}
+		if p.Category != category || (r.Category != category && (strictFilters || r.Category != "ALL")) {
+			continue
+		}
+		if (day == 1 || day == 2) && r.Day != day {
+			continue
+		}
+		if year > 0 && r.date.Year() != int(year) {
+			continue
+		}
+
+		branches := make([]string, 0, len(p.ClubNames))
+		for _, name := range p.ClubNames {
+			if letter := crewBranch(name); letter != "" {
+				branches = append(branches, letter)
+			}
+		}
+		switch branch {
+		case BRANCH_ALL:
+			if leagueID > 0 && flagID > 0 && len(branches) > 0 {
+				continue
+			}
+		case BRANCH_MAIN:
+			if len(branches) > 0 {
+				continue
+			}
+		case BRANCH_TEAMS:
+			if len(branches) == 0 {
+				continue
+			}
+		default:
+			if !arrays.Contains(branches, branch) {
+				continue
+			}
+		}
+
+		if onlyLeagueRaces && r.LeagueID == nil {
+			continue
+		}
+		if clubID > 0 && p.ClubID != clubID {
+			continue
+		}
+		if leagueID > 0 && (r.LeagueID == nil || *r.LeagueID != leagueID) {
+			continue
+		}
+		if flagID > 0 && (r.FlagID == nil || *r.FlagID != flagID) {
+			continue
+		}
+
+		speed := speedOf(p)
+		if applyPenalties {
+			speed = m.penalizedSpeedOf(p)
+		}
+		entries = append(entries, speedEntry{race: r, participant: p, speed: speed})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].race.date.Equal(entries[j].race.date) {
+			return entries[i].race.date.Before(entries[j].race.date)
+		}
+		return entries[i].speed > entries[j].speed
+	})
+
+	return entries, nil
+}
+
+func excludeParticipants(entries []speedEntry, excluded []int64) []speedEntry {
+	if len(excluded) == 0 {
+		return entries
+	}
+
+	kept := make([]speedEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !arrays.Contains(excluded, entry.participant.ID) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// normalizeSpeeds drops the speeds outside two population standard deviations from the mean.
+func normalizeSpeeds(entries []speedEntry) []speedEntry {
+	if len(entries) == 0 {
+		return entries
+	}
+
+	sum := 0.0
+	for _, entry := range entries {
+		sum += entry.speed
+	}
+	mean := sum / float64(len(entries))
+
+	variance := 0.0
+	for _, entry := range entries {
+		variance += (entry.speed - mean) * (entry.speed - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(entries)))
+
+	normalized := make([]speedEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.speed >= mean-2*stddev && entry.speed <= mean+2*stddev {
+			normalized = append(normalized, entry)
+		}
+	}
+	return normalized
+}
+
+func (m *MemoryRepository) raceRow(race *MemoryRace) RaceRow {
+	row := RaceRow{
+		ID:            race.ID,
+		TrophyID:      race.TrophyID,
+		TrophyEdition: race.TrophyEdition,
+		FlagID:        race.FlagID,
+		FlagEdition:   race.FlagEdition,
+		LeagueID:      race.LeagueID,
+		AssociatedID:  race.AssociatedID,
+		Day:           race.Day,
+		Date:          pgtype.Date{Time: race.date, Status: pgtype.Present},
+		Gender:        race.Gender,
+		Type:          race.Type,
+		Modality:      race.Modality,
+		Laps:          race.Laps,
+		Lanes:         race.Lanes,
+		IsCancelled:   race.IsCancelled,
+		Sponsor:       race.Sponsor,
+		Metadata:      race.Metadata,
+	}
+
+	if trophy := m.trophy(race.TrophyID); trophy != nil {
+		row.TrophyName = &trophy.Name
+	}
+	if flag := m.flag(race.FlagID); flag != nil {
+		row.FlagName = &flag.Name
+	}
+	if league := m.league(race.LeagueID); league != nil {
+		row.LeagueName = &league.Name
+		row.LeagueGender = league.Gender
+		row.LeagueCategory = league.Category
+	}
+
+	for _, participant := range m.participantsOf(race.ID) {
+		if participant.Series != nil && (row.Series == nil || *participant.Series > *row.Series) {
+			row.Series = participant.Series
+		}
+	}
+
+	return row
+}
+
+func (m *MemoryRepository) participantRow(participant *MemoryParticipant) ParticipantRow {
+	row := ParticipantRow{
+		ID:             participant.ID,
+		RaceID:         participant.RaceID,
+		Gender:         participant.Gender,
+		Category:       participant.Category,
+		ClubId:         participant.ClubID,
+		ClubRawNames:   (*pq.StringArray)(&participant.ClubNames),
+		IsDisqualified: m.disqualified[participant.ID],
+		IsRetired:      participant.IsRetired,
+		IsGuest:        participant.IsGuest,
+		IsAbsent:       participant.IsAbsent,
+		Laps:           (*pq.StringArray)(&participant.Laps),
+		Lane:           participant.Lane,
+		Series:         participant.Series,
+	}
+	if participant.Distance != nil {
+		row.Distance = *participant.Distance
+	}
+	if entity, ok := m.entities[participant.ClubID]; ok {
+		row.ClubName = entity.Name
+	}
+	return row
+}
+
+func (m *MemoryRepository) participantsOf(raceID int64) []*MemoryParticipant {
+	participants := make([]*MemoryParticipant, 0)
+	for i := range m.data.Participants {
+		if m.data.Participants[i].RaceID == raceID {
+			participants = append(participants, &m.data.Participants[i])
+		}
+	}
+	return participants
+}
+
+func (m *MemoryRepository) trophy(id *int64) *MemoryTrophy {
+	if id == nil {
+		return nil
+	}
+	return m.trophies[*id]
+}
+
+func (m *MemoryRepository) flag(id *int64) *MemoryFlag {
+	if id == nil {
+		return nil
+	}
+	return m.flags[*id]
+}
+
+func (m *MemoryRepository) league(id *int64) *MemoryLeague {
+	if id == nil {
+		return nil
+	}
+	return m.leagues[*id]
+}
+
+func hasSpeed(participant *MemoryParticipant) bool {
+	return participant.hasTime && participant.time > 0 && participant.Distance != nil
+}
+
+func speedOf(participant *MemoryParticipant) float64 {
+	return (float64(*participant.Distance) / participant.time.Seconds()) * 3.6
+}
+
+func (m *MemoryRepository) penalizedSpeedOf(participant *MemoryParticipant) float64 {
+	return (float64(*participant.Distance) / (participant.time + m.penaltyTime[participant.ID]).Seconds()) * 3.6
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -1,13 +1,13 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	sq "github.com/Masterminds/squirrel"
-	"github.com/iagocanalejas/rstats/internal/utils/assert"
 	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
-	"github.com/iagocanalejas/rstats/internal/utils/strings"
+	"github.com/jackc/pgx/pgtype"
 	"github.com/lib/pq"
 )
 
@@ -24,28 +24,34 @@ type ParticipantRow struct {
 	ClubRawNames *pq.StringArray `db:"club_raw_names"`
 
 	IsDisqualified bool `db:"disqualified"`
+	IsRetired      bool `db:"retired"`
+	IsGuest        bool `db:"guest"`
+	IsAbsent       bool `db:"absent"`
 
 	Laps   *pq.StringArray `db:"laps"`
 	Lane   *int16          `db:"lane"`
 	Series *int16          `db:"series"`
 }
 
-func (r *Repository) GetParticipantsByRaceID(raceID int64) ([]ParticipantRow, error) {
+func (r *PostgresRepository) GetParticipantsByRaceID(ctx context.Context, raceID int64) ([]ParticipantRow, error) {
 	query, args, err := sq.
 		Select("p.id", "p.race_id", "p.gender", "p.category", "p.distance", "p.laps", "p.lane", "p.series",
+			"p.retired", "p.guest", "p.absent",
 			"p.club_id as club_id", "e.name as club_name", "p.club_names as club_raw_names",
 			"((SELECT count(*) FROM penalty pe WHERE pe.participant_id = p.id AND disqualification) > 0) as disqualified").
 		From("participant p").
 		LeftJoin("entity e ON p.club_id = e.id").
 		Where(sq.Eq{"p.race_id": raceID}).
-		OrderBy("p.laps[ARRAY_UPPER(p.laps, 1)] ASC").
+		OrderBy("disqualified", "p.laps[ARRAY_UPPER(p.laps, 1)] ASC"). // disqualified crews are not classified
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
-	assert.NoError(err, "building query=%s args=%s", query, args)
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
 
-	var participants []ParticipantRow
-	if err = r.db.Select(&participants, query, args...); err != nil {
-		participants = make([]ParticipantRow, 0)
+	participants := make([]ParticipantRow, 0)
+	if err = r.db.SelectContext(ctx, &participants, query, args...); err != nil {
+		return nil, queryError(err, "loading participants for race=%d", raceID)
 	}
 
 	return participants, nil
@@ -69,13 +75,16 @@ type ParticipantRowWithSpeed struct {
 	Lane   *int16          `db:"lane"`
 	Series *int16          `db:"series"`
 
-	Speed float64 `db:"speed"`
+	Speed float64     `db:"speed"`
+	Date  pgtype.Date `db:"date"` // of the race
 }
 
-func (r *Repository) GetParticipantsWithSpeed() ([]ParticipantRowWithSpeed, error) {
+// GetParticipantsWithSpeed loads the speed of every classified participant in chronological order, grouped by race,
+// gender and category. An empty category loads all of them.
+func (r *PostgresRepository) GetParticipantsWithSpeed(ctx context.Context, category string) ([]ParticipantRowWithSpeed, error) {
 	rawQuery := `
 		SELECT
-			p.id, p.race_id, p.gender, p.category, p.distance, p.laps, p.lane, p.series,
+			p.id, p.race_id, p.gender, p.category, p.distance, p.laps, p.lane, p.series, r.date,
 			p.club_id as club_id, e.name as club_name, p.club_names as club_raw_names,
 			CAST((p.distance / (extract(EPOCH FROM p.laps[cardinality(p.laps)]))) * 3.6 AS DOUBLE PRECISION) AS speed
 		FROM participant p
@@ -86,26 +95,33 @@ func (r *Repository) GetParticipantsWithSpeed() ([]ParticipantRowWithSpeed, erro
 			AND NOT r.cancelled
 			AND NOT p.retired
 			AND NOT p.guest
-			AND p.category = 'ABSOLUT'
+			AND ($1::text = '' OR p.category = $1)
 			AND (extract(EPOCH FROM p.laps[cardinality(p.laps)]) > 0)
 			AND NOT EXISTS(SELECT * FROM penalty WHERE participant_id = p.id AND disqualification)
-		ORDER BY p.race_id, p.gender, p.category;
+		ORDER BY r.date, p.race_id, p.gender, p.category;
 	`
 	prettylog.Debug("%s", rawQuery)
 
-	rows, err := r.db.Query(rawQuery)
-	assert.NoError(err, "failed to execute query=%s", rawQuery)
+	rows, err := r.db.QueryContext(ctx, rawQuery, category)
+	if err != nil {
+		return nil, queryError(err, "executing query=%s", rawQuery)
+	}
 	defer rows.Close()
 
 	participants := make([]ParticipantRowWithSpeed, 0)
 
 	var p ParticipantRowWithSpeed
 	for rows.Next() {
-		err := rows.Scan(&p.ID, &p.RaceID, &p.Gender, &p.Category, &p.Distance, &p.Laps, &p.Lane, &p.Series, &p.ClubId, &p.ClubName, &p.ClubRawNames, &p.Speed)
-		assert.NoError(err, "failed to scan row participant=%v", p)
+		err := rows.Scan(&p.ID, &p.RaceID, &p.Gender, &p.Category, &p.Distance, &p.Laps, &p.Lane, &p.Series, &p.Date, &p.ClubId, &p.ClubName, &p.ClubRawNames, &p.Speed)
+		if err != nil {
+			return nil, queryError(err, "scanning row participant=%v", p)
+		}
 
 		participants = append(participants, p)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, queryError(err, "reading participants with speed")
+	}
 
 	return participants, nil
 }
@@ -118,9 +134,11 @@ type GetYearSpeedsByParams struct {
 	Category        string
 	Day             int16
 	Years           []int
-	BranchTeams     bool
+	Branch          string // BRANCH_MAIN, BRANCH_TEAMS or a single branch letter, see [BRANCH_ALL]
 	OnlyLeagueRaces bool
 	Normalize       bool
+	ApplyPenalties  bool    // add the time penalties to the participant times
+	Excluded        []int64 // participants left out of the normalized speeds, i.e. reviewed outliers
 }
 
 // GetYearSpeedsBy retrieves the speeds of participants grouped by year based on the provided filtering criteria.
@@ -130,62 +148,59 @@ type GetYearSpeedsByParams struct {
 //  1. **Speed Calculation**: Speed is calculated for each participant by dividing the race distance by the time taken,
 //     then converting the result to km/h.
 //  2. **Subquery**: Filters are applied to the races and participants based on the provided parameters (e.g., ClubID, LeagueID).
-//  3. **Normalization** (optional): If enabled, speeds that fall outside two standard deviations from the mean are excluded.
+//  3. **Normalization** (optional): If enabled, the excluded participants and the speeds that fall outside two standard
+//     deviations from the mean are left out.
 //  4. **Main Query**: Aggregates speeds for each year using `array_agg`, and groups the results by year.
-func (r *Repository) GetYearSpeedsBy(params *GetYearSpeedsByParams) ([]int, *map[int][]float64, error) {
-	subqueryWhere := getSpeedFilters(
+func (r *PostgresRepository) GetYearSpeedsBy(ctx context.Context, params *GetYearSpeedsByParams) ([]int, *map[int][]float64, error) {
+	filters, err := getSpeedFilters(
 		params.ClubID, params.LeagueID, params.FlagID,
 		params.Gender, params.Category,
 		params.Day,
-		params.BranchTeams, params.OnlyLeagueRaces,
+		params.Branch, params.OnlyLeagueRaces,
 	)
-	speedExpression := "(p.distance / (extract(EPOCH FROM p.laps[cardinality(p.laps)]))) * 3.6"
+	if err != nil {
+		return nil, nil, err
+	}
+
+	speedsQuery := sq.
+		Select("extract(YEAR from date)::INTEGER as year", fmt.Sprintf("CAST(%s AS DOUBLE PRECISION) as speed", speedColumn(params.ApplyPenalties))).
+		From("participant p").
+		Join("race r ON p.race_id = r.id").
+		Where(filters).
+		OrderBy("r.date", "speed DESC")
+
+	if params.Normalize && len(params.Excluded) > 0 {
+		speedsQuery = speedsQuery.Where(sq.NotEq{"p.id": params.Excluded})
+	}
+
+	baseSelect := sq.
+		Select("year", "array_agg(speed) AS speeds").
+		PrefixExpr(sq.Expr("WITH speeds_query AS (?)", speedsQuery)).
+		From("speeds_query")
 
-	whereClause := ""
 	if len(params.Years) > 0 {
-		whereClause = fmt.Sprintf("WHERE year in (%s)", utils.IntSlice2String(params.Years))
+		baseSelect = baseSelect.Where(sq.Eq{"year": params.Years})
 	}
 
 	if params.Normalize {
-		normalizeClause := `
-			speed BETWEEN
-			(
-				SELECT AVG(speed) - (2 * STDDEV_POP(speed))
-				FROM speeds_query
-			)
-			AND
-			(
-				SELECT AVG(speed) + (2 * STDDEV_POP(speed))
-				FROM speeds_query
-			)
-		`
-		if whereClause != "" {
-			whereClause = whereClause + " AND " + normalizeClause
-		} else {
-			whereClause = "WHERE " + normalizeClause
-		}
+		baseSelect = baseSelect.Where(normalizeClause)
 	}
 
-	rawQuery := fmt.Sprintf(`
-		WITH speeds_query AS (
-            SELECT
-                extract(YEAR from date)::INTEGER as year,
-                CAST(%s AS DOUBLE PRECISION) as speed
-            FROM participant p JOIN race r ON p.race_id = r.id
-            WHERE %s
-            ORDER BY r.date, speed DESC
-        )
-        SELECT year, array_agg(speed) AS speeds
-        FROM speeds_query
-        %s
-        GROUP BY year
-        ORDER BY year;
-	`, speedExpression, subqueryWhere, whereClause)
+	query, args, err := baseSelect.
+		GroupBy("year").
+		OrderBy("year").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, nil, queryError(err, "building query=%s args=%v", query, args)
+	}
 
-	prettylog.Debug("%s", rawQuery)
+	prettylog.Debug("%s %v", query, args)
 
-	rows, err := r.db.Query(rawQuery)
-	assert.NoError(err, "failed to execute query=%s", rawQuery)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, queryError(err, "executing query=%s args=%v", query, args)
+	}
 	defer rows.Close()
 
 	years := make([]int, 0)
@@ -196,15 +211,83 @@ func (r *Repository) GetYearSpeedsBy(params *GetYearSpeedsByParams) ([]int, *map
 
 	for rows.Next() {
 		err := rows.Scan(&year, pq.Array(&speedArray))
-		assert.NoError(err, "failed to scan row year=%d speeds=%f", year, speedArray)
+		if err != nil {
+			return nil, nil, queryError(err, "scanning row year=%d speeds=%f", year, speedArray)
+		}
 
 		years = append(years, year)
 		speeds[year] = append([]float64(nil), speedArray...)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, queryError(err, "reading year speeds")
+	}
 
 	return years, &speeds, nil
 }
 
+type DatedSpeedRow struct {
+	ParticipantID int64       `db:"participant_id"`
+	RaceID        int64       `db:"race_id"`
+	ClubID        int64       `db:"club_id"`
+	Date          pgtype.Date `db:"date"` // of the race
+	Speed         float64     `db:"speed"`
+}
+
+// GetDatedSpeedsBy retrieves the speed of each participant with the date of its race, using the same filters and
+// normalization as [PostgresRepository.GetYearSpeedsBy]. Speeds are in chronological order, fastest first within a race.
+func (r *PostgresRepository) GetDatedSpeedsBy(ctx context.Context, params *GetYearSpeedsByParams) ([]DatedSpeedRow, error) {
+	filters, err := getSpeedFilters(
+		params.ClubID, params.LeagueID, params.FlagID,
+		params.Gender, params.Category,
+		params.Day,
+		params.Branch, params.OnlyLeagueRaces,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	speedsQuery := sq.
+		Select("p.id as participant_id", "p.race_id", "p.club_id", "r.date",
+			fmt.Sprintf("CAST(%s AS DOUBLE PRECISION) as speed", speedColumn(params.ApplyPenalties))).
+		From("participant p").
+		Join("race r ON p.race_id = r.id").
+		Where(filters)
+
+	if params.Normalize && len(params.Excluded) > 0 {
+		speedsQuery = speedsQuery.Where(sq.NotEq{"p.id": params.Excluded})
+	}
+
+	baseSelect := sq.
+		Select("participant_id", "race_id", "club_id", "date", "speed").
+		PrefixExpr(sq.Expr("WITH speeds_query AS (?)", speedsQuery)).
+		From("speeds_query")
+
+	if len(params.Years) > 0 {
+		baseSelect = baseSelect.Where(sq.Eq{"extract(YEAR FROM date)::INTEGER": params.Years})
+	}
+
+	if params.Normalize {
+		baseSelect = baseSelect.Where(normalizeClause)
+	}
+
+	query, args, err := baseSelect.
+		OrderBy("date", "race_id", "speed DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+
+	prettylog.Debug("%s %v", query, args)
+
+	speeds := make([]DatedSpeedRow, 0)
+	if err = r.db.SelectContext(ctx, &speeds, query, args...); err != nil {
+		return nil, queryError(err, "loading dated speeds params=%v", *params)
+	}
+
+	return speeds, nil
+}
+
 type GetNthSpeedsByParams struct {
 	Index           int // the index is one-based as postgresql arrays are one-based
 	ClubID          int64
@@ -213,9 +296,11 @@ type GetNthSpeedsByParams struct {
 	Category        string
 	Day             int16
 	Year            int16
-	BranchTeams     bool
+	Branch          string // BRANCH_MAIN, BRANCH_TEAMS or a single branch letter, see [BRANCH_ALL]
 	OnlyLeagueRaces bool
 	Normalize       bool
+	ApplyPenalties  bool    // add the time penalties to the participant times, which may change the ranking
+	Excluded        []int64 // participants left out of the normalized speeds, i.e. reviewed outliers
 }
 
 // GetNthSpeedsBy retrieves the N-th highest speed for each race based on the provided filtering criteria.
@@ -228,52 +313,61 @@ type GetNthSpeedsByParams struct {
 //  2. **Subquery**: Filters are applied to the races and participants based on the provided parameters (e.g., ClubID, Gender, Year).
 //  3. **Normalization** (optional): If normalization is enabled, speeds outside two standard deviations from the mean are excluded.
 //  4. **Main Query**: Retrieves the N-th highest speed for each race using `array_agg` and returns only races where there are at least N speeds.
-func (r *Repository) GetNthSpeedsBy(params *GetNthSpeedsByParams) ([]float64, error) {
-	assert.Assert(params.Index > 0, "no index provided %v", *params)
-	assert.Assert(params.Year > 0, "no year provided %v", *params)
+func (r *PostgresRepository) GetNthSpeedsBy(ctx context.Context, params *GetNthSpeedsByParams) ([]float64, error) {
+	if params.Index <= 0 {
+		return nil, filterError("no index provided %v", *params)
+	}
+	if params.Year <= 0 {
+		return nil, filterError("no year provided %v", *params)
+	}
 
-	subqueryWhere := getSpeedFilters(
+	filters, err := getSpeedFilters(
 		params.ClubID, params.LeagueID, 0,
 		params.Gender, params.Category,
 		params.Day,
-		params.BranchTeams, params.OnlyLeagueRaces,
+		params.Branch, params.OnlyLeagueRaces,
 	)
-	subqueryWhere += fmt.Sprintf(" AND extract(YEAR FROM r.date) = %d", params.Year)
-	speedExpression := "(p.distance / (extract(EPOCH FROM p.laps[cardinality(p.laps)]))) * 3.6"
+	if err != nil {
+		return nil, err
+	}
+
+	speedsQuery := sq.
+		Select("p.race_id", fmt.Sprintf("CAST(%s AS DOUBLE PRECISION) as speed", speedColumn(params.ApplyPenalties))).
+		From("participant p").
+		Join("race r ON p.race_id = r.id").
+		Where(filters).
+		Where(sq.Eq{"extract(YEAR FROM r.date)": params.Year}).
+		OrderBy("r.date")
+
+	if params.Normalize && len(params.Excluded) > 0 {
+		speedsQuery = speedsQuery.Where(sq.NotEq{"p.id": params.Excluded})
+	}
+
+	baseSelect := sq.
+		Select("race_id").
+		Column("(array_agg(speed ORDER BY speed DESC))[?] AS speed", params.Index).
+		PrefixExpr(sq.Expr("WITH speeds_query AS (?)", speedsQuery)).
+		From("speeds_query")
 
-	whereClause := ""
 	if params.Normalize {
-		whereClause = `
-            WHERE speed BETWEEN (
-                SELECT AVG(speed) - (2 * STDDEV_POP(speed))
-                FROM speeds_query
-            ) AND (
-                SELECT AVG(speed) + (2 * STDDEV_POP(speed))
-                FROM speeds_query
-            )
-		`
-	}
-
-	rawQuery := fmt.Sprintf(`
-        WITH speeds_query AS (
-            SELECT
-                p.race_id,
-                CAST(%s AS DOUBLE PRECISION) as speed
-            FROM participant p JOIN race r ON p.race_id = r.id
-            WHERE %s
-            ORDER BY r.date
-        )
-        SELECT race_id, (array_agg(speed ORDER BY speed DESC))[%d] AS speed
-        FROM speeds_query
-		%s
-        GROUP BY race_id
-        HAVING array_length(array_agg(speed), 1) >= %d;
-	`, speedExpression, subqueryWhere, params.Index, whereClause, params.Index)
+		baseSelect = baseSelect.Where(normalizeClause)
+	}
 
-	prettylog.Debug("%s", rawQuery)
+	query, args, err := baseSelect.
+		GroupBy("race_id").
+		Having("array_length(array_agg(speed), 1) >= ?", params.Index).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+
+	prettylog.Debug("%s %v", query, args)
 
-	rows, err := r.db.Query(rawQuery)
-	assert.NoError(err, "failed to execute query=%s", rawQuery)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError(err, "executing query=%s args=%v", query, args)
+	}
 	defer rows.Close()
 
 	speeds := make([]float64, 0)
@@ -282,70 +376,203 @@ func (r *Repository) GetNthSpeedsBy(params *GetNthSpeedsByParams) ([]float64, er
 	var speed float64
 	for rows.Next() {
 		err := rows.Scan(&raceID, &speed)
-		assert.NoError(err, "failed to scan row raceID=%d speed=%f", raceID, speed)
+		if err != nil {
+			return nil, queryError(err, "scanning row raceID=%d speed=%f", raceID, speed)
+		}
 
 		speeds = append(speeds, speed)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, queryError(err, "reading nth speeds")
+	}
 
 	return speeds, nil
 }
 
+type ParticipantLapsRow struct {
+	ID       int64          `db:"id"`
+	RaceID   int64          `db:"race_id"`
+	Date     pgtype.Date    `db:"date"`
+	RaceLaps int16          `db:"race_laps"`
+	Distance int            `db:"distance"`
+	Laps     pq.StringArray `db:"laps"`
+}
+
+type GetParticipantLapsByParams struct {
+	ClubID   int64
+	LeagueID int64
+	Gender   string
+	Category string
+	Year     int16
+}
+
+// GetParticipantLapsBy retrieves the laps of the classified participants matching the filters, only the participants
+// with a time for every lap of the race are returned. It uses the same filters as the speed queries.
+func (r *PostgresRepository) GetParticipantLapsBy(ctx context.Context, params *GetParticipantLapsByParams) ([]ParticipantLapsRow, error) {
+	filters, err := getSpeedFilters(
+		params.ClubID, params.LeagueID, 0,
+		params.Gender, params.Category,
+		0,
+		BRANCH_ALL, false,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	baseSelect := sq.
+		Select("p.id", "p.race_id", "r.date", "r.laps as race_laps", "p.distance", "p.laps").
+		From("participant p").
+		Join("race r ON p.race_id = r.id").
+		Where(filters).
+		Where(sq.Expr("r.laps IS NOT NULL")).
+		Where(sq.Expr("cardinality(p.laps) = r.laps"))
+
+	if params.Year > 0 {
+		baseSelect = baseSelect.Where(sq.Eq{"extract(YEAR FROM r.date)": params.Year})
+	}
+
+	query, args, err := baseSelect.
+		OrderBy("r.date", "p.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+
+	prettylog.Debug("%s %v", query, args)
+
+	participants := make([]ParticipantLapsRow, 0)
+	if err = r.db.SelectContext(ctx, &participants, query, args...); err != nil {
+		return nil, queryError(err, "loading participant laps params=%v", *params)
+	}
+
+	return participants, nil
+}
+
+const (
+	// BRANCH FILTERS
+	BRANCH_ALL     = ""       // all the crews, but branch teams are left out when filtering by both league and flag
+	BRANCH_MAIN    = "main"   // only the main crews
+	BRANCH_TEAMS   = "branch" // only the branch teams
+	BRANCH_LETTERS = "BCDE"   // letters after the club name of the branch teams, "PUEBLA B", also valid as a filter
+)
+
+const (
+	// speed of a participant in km/h computed from the time of the last lap
+	speedExpression = "(p.distance / (extract(EPOCH FROM p.laps[cardinality(p.laps)]))) * 3.6"
+
+	// same as speedExpression adding the time penalties of the participant
+	penalizedSpeedExpression = `(p.distance / (
+		extract(EPOCH FROM p.laps[cardinality(p.laps)])
+		+ COALESCE((SELECT SUM(pe.penalty) FROM penalty pe WHERE pe.participant_id = p.id), 0)
+	)) * 3.6`
+
+	// raw club name without the sponsor, which goes after a " - " or in parentheses
+	crewNameExpression = `regexp_replace(club_name, ' (- |\().*$', '')`
+
+	// participants with a raw club name whose crew name matches the pattern parameter
+	branchClause = "EXISTS(SELECT 1 FROM unnest(p.club_names) AS club_name WHERE " + crewNameExpression + " ~ ?)"
+
+	// crew names ending with a branch letter, see [BRANCH_LETTERS]
+	branchPattern = " [B-E]$"
+
+	// keeps only the speeds within two standard deviations from the mean of the `speeds_query` CTE
+	normalizeClause = `speed BETWEEN (
+		SELECT AVG(speed) - (2 * STDDEV_POP(speed))
+		FROM speeds_query
+	) AND (
+		SELECT AVG(speed) + (2 * STDDEV_POP(speed))
+		FROM speeds_query
+	)`
+)
+
+func speedColumn(applyPenalties bool) string {
+	if applyPenalties {
+		return penalizedSpeedExpression
+	}
+	return speedExpression
+}
+
 func getSpeedFilters(
 	clubID, leagueID, flagID int64,
 	gender, category string,
 	day int16,
-	branchTeams, onlyLeagueRaces bool,
-) string {
-	assert.Assert(gender != "", "invalid gender")
-	assert.Assert(category != "", "invalid category")
-	assert.Assert(day == 0 || day == 1 || day == 2, "invalid day=%d", day)
+	branch string,
+	onlyLeagueRaces bool,
+) (sq.And, error) {
+	if err := validateSpeedFilters(gender, category, day, branch); err != nil {
+		return nil, err
+	}
 
-	genderFilter := fmt.Sprintf("(p.gender = '%s' AND (r.gender = '%s' OR r.gender = '%s'))", gender, gender, "ALL")
+	genderFilter := sq.And{sq.Eq{"p.gender": gender}, sq.Eq{"r.gender": []string{gender, "ALL"}}}
 	if onlyLeagueRaces || leagueID > 0 {
-		genderFilter = fmt.Sprintf("(p.gender = '%s' AND r.gender = '%s')", gender, gender)
+		genderFilter = sq.And{sq.Eq{"p.gender": gender}, sq.Eq{"r.gender": gender}}
 	}
 
-	categoryFilter := fmt.Sprintf("(p.category = '%s' AND (r.category = '%s' OR r.category = '%s'))", category, category, "ALL")
+	categoryFilter := sq.And{sq.Eq{"p.category": category}, sq.Eq{"r.category": []string{category, "ALL"}}}
 	if onlyLeagueRaces || leagueID > 0 {
-		categoryFilter = fmt.Sprintf("(p.category = '%s' AND r.category = '%s')", category, category)
-	}
-
-	filters := []string{
-		"NOT r.cancelled",
-		"p.laps <> '{}'",
-		"NOT p.retired",
-		"NOT p.guest",
-		"NOT p.absent",
-		"p.distance IS NOT NULL",
-		"(extract(EPOCH FROM p.laps[cardinality(p.laps)])) > 0",                              // avoid division by zero
-		"NOT EXISTS(SELECT * FROM penalty WHERE participant_id = p.id AND disqualification)", // avoid disqualifications
+		categoryFilter = sq.And{sq.Eq{"p.category": category}, sq.Eq{"r.category": category}}
+	}
+
+	filters := sq.And{
+		sq.Expr("NOT r.cancelled"),
+		sq.Expr("p.laps <> '{}'"),
+		sq.Expr("NOT p.retired"),
+		sq.Expr("NOT p.guest"),
+		sq.Expr("NOT p.absent"),
+		sq.Expr("p.distance IS NOT NULL"),
+		sq.Expr("(extract(EPOCH FROM p.laps[cardinality(p.laps)])) > 0"),                              // avoid division by zero
+		sq.Expr("NOT EXISTS(SELECT * FROM penalty WHERE participant_id = p.id AND disqualification)"), // avoid disqualifications
 		genderFilter,
 		categoryFilter,
 	}
 
 	if day == 1 || day == 2 {
-		filters = append(filters, fmt.Sprintf("r.day = %d", day))
+		filters = append(filters, sq.Eq{"r.day": day})
 	}
 
-	if branchTeams {
-		filters = append(filters, "EXISTS(SELECT 1 FROM unnest(p.club_names) AS club_name WHERE club_name LIKE '% B')")
-	} else if leagueID > 0 && flagID > 0 {
-		filters = append(filters, "(p.club_names = '{}' OR NOT EXISTS(SELECT 1 FROM unnest(p.club_names) AS club_name WHERE club_name LIKE '% B'))")
+	switch branch {
+	case BRANCH_ALL:
+		if leagueID > 0 && flagID > 0 {
+			filters = append(filters, sq.Expr("NOT "+branchClause, branchPattern))
+		}
+	case BRANCH_MAIN:
+		filters = append(filters, sq.Expr("NOT "+branchClause, branchPattern))
+	case BRANCH_TEAMS:
+		filters = append(filters, sq.Expr(branchClause, branchPattern))
+	default:
+		filters = append(filters, sq.Expr(branchClause, " "+branch+"$"))
 	}
 
 	if onlyLeagueRaces {
-		filters = append(filters, "r.league_id IS NOT NULL")
+		filters = append(filters, sq.Expr("r.league_id IS NOT NULL"))
 	}
 
 	if clubID > 0 {
-		filters = append(filters, fmt.Sprintf("p.club_id = %d", clubID))
+		filters = append(filters, sq.Eq{"p.club_id": clubID})
 	}
 	if leagueID > 0 {
-		filters = append(filters, fmt.Sprintf("r.league_id = %d", leagueID))
+		filters = append(filters, sq.Eq{"r.league_id": leagueID})
 	}
 	if flagID > 0 {
-		filters = append(filters, fmt.Sprintf("r.flag_id = %d", flagID))
+		filters = append(filters, sq.Eq{"r.flag_id": flagID})
 	}
 
-	return strings.Join(filters, " AND ")
+	return filters, nil
+}
+
+func validateSpeedFilters(gender, category string, day int16, branch string) error {
+	if gender == "" {
+		return filterError("invalid gender")
+	}
+	if category == "" {
+		return filterError("invalid category")
+	}
+	if day != 0 && day != 1 && day != 2 {
+		return filterError("invalid day=%d", day)
+	}
+	if branch != BRANCH_ALL && branch != BRANCH_MAIN && branch != BRANCH_TEAMS && (len(branch) != 1 || !strings.Contains(BRANCH_LETTERS, branch)) {
+		return filterError("invalid branch=%s", branch)
+	}
+	return nil
 }
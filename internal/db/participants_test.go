@@ -0,0 +1,127 @@
+package db
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func TestSpeedFiltersBindValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		gender   string
+		category string
+	}{
+		{"quote", "O'Grove", "ABSOLUT"},
+		{"percent", "MALE", "100%"},
+		{"underscore", "A_B", "ABSOLUT"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filters, err := getSpeedFilters(0, 0, 0, test.gender, test.category, 0, BRANCH_MAIN, false)
+			if err != nil {
+				t.Fatalf("getSpeedFilters: %v", err)
+			}
+			query, args, err := sq.Select("p.id").From("participant p").Where(filters).PlaceholderFormat(sq.Dollar).ToSql()
+			if err != nil {
+				t.Fatalf("ToSql: %v", err)
+			}
+
+			// gender and category are compared for equality, so they are bound as they are
+			for _, value := range []string{test.gender, test.category} {
+				if strings.Contains(query, value) {
+					t.Errorf("query contains %q: %s", value, query)
+				}
+				if !slices.Contains(args, any(value)) {
+					t.Errorf("args=%v, want %q", args, value)
+				}
+			}
+		})
+	}
+}
+
+func TestSpeedFiltersInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		gender   string
+		category string
+		day      int16
+		branch   string
+	}{
+		{"no gender", "", "ABSOLUT", 0, BRANCH_ALL},
+		{"no category", "MALE", "", 0, BRANCH_ALL},
+		{"day", "MALE", "ABSOLUT", 3, BRANCH_ALL},
+		{"branch pattern", "MALE", "ABSOLUT", 0, "%"},
+		{"branch letters", "MALE", "ABSOLUT", 0, "AB"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := getSpeedFilters(0, 0, 0, test.gender, test.category, test.day, test.branch, false)
+			if !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("getSpeedFilters(%q, %q, %d, %q) err=%v, want %v", test.gender, test.category, test.day, test.branch, err, ErrInvalidFilter)
+			}
+		})
+	}
+}
+
+func TestSpeedFiltersBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		leagueID int64
+		flagID   int64
+		branch   string
+		want     string // bound pattern, empty when the crews are not filtered
+		negated  bool
+	}{
+		{"all", 0, 0, BRANCH_ALL, "", false},
+		{"all by league and flag", 1, 1, BRANCH_ALL, branchPattern, true},
+		{"main", 0, 0, BRANCH_MAIN, branchPattern, true},
+		{"teams", 0, 0, BRANCH_TEAMS, branchPattern, false},
+		{"letter", 0, 0, "C", " C$", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filters, err := getSpeedFilters(0, test.leagueID, test.flagID, "MALE", "ABSOLUT", 0, test.branch, false)
+			if err != nil {
+				t.Fatalf("getSpeedFilters: %v", err)
+			}
+			query, args, err := sq.Select("p.id").From("participant p").Where(filters).PlaceholderFormat(sq.Dollar).ToSql()
+			if err != nil {
+				t.Fatalf("ToSql: %v", err)
+			}
+
+			if test.want == "" {
+				if strings.Contains(query, "unnest(p.club_names)") {
+					t.Errorf("query filters the crews: %s", query)
+				}
+				return
+			}
+			if !slices.Contains(args, any(test.want)) {
+				t.Errorf("args=%v, want %q", args, test.want)
+			}
+			if negated := strings.Contains(query, "NOT EXISTS(SELECT 1 FROM unnest(p.club_names)"); negated != test.negated {
+				t.Errorf("negated=%t, want %t: %s", negated, test.negated, query)
+			}
+		})
+	}
+}
+
+// TestBranchPattern keeps the branch pattern in step with BRANCH_LETTERS, the crew names are the raw club names
+// without the sponsor as in crewNameExpression.
+func TestBranchPattern(t *testing.T) {
+	pattern := regexp.MustCompile(branchPattern)
+	for _, letter := range BRANCH_LETTERS {
+		if name := "PUEBLA " + string(letter); !pattern.MatchString(name) {
+			t.Errorf("%q does not match %q", name, branchPattern)
+		}
+	}
+	for _, name := range []string{"PUEBLA", "PUEBLA A", "X F", "PUEBLA BC", "B"} {
+		if pattern.MatchString(name) {
+			t.Errorf("%q matches %q", name, branchPattern)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type PenaltyRow struct {
+	ID            int64 `db:"id"`
+	ParticipantID int64 `db:"participant_id"`
+
+	Penalty          int     `db:"penalty"` // seconds added to the participant time
+	Disqualification bool    `db:"disqualification"`
+	Reason           *string `db:"reason"`
+}
+
+func (r *PostgresRepository) GetPenaltiesByRaceID(ctx context.Context, raceID int64) ([]PenaltyRow, error) {
+	query, args, err := sq.
+		Select("pe.id", "pe.participant_id", "COALESCE(pe.penalty, 0) as penalty", "pe.disqualification", "pe.reason").
+		From("penalty pe").
+		Join("participant p ON p.id = pe.participant_id").
+		Where(sq.Eq{"p.race_id": raceID}).
+		OrderBy("pe.participant_id", "pe.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+
+	penalties := make([]PenaltyRow, 0)
+	if err = r.db.SelectContext(ctx, &penalties, query, args...); err != nil {
+		return nil, queryError(err, "loading penalties for race=%d", raceID)
+	}
+
+	return penalties, nil
+}
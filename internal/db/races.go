@@ -1,10 +1,11 @@
 package db
 
 import (
-	"fmt"
+	"context"
+	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
-	"github.com/iagocanalejas/rstats/internal/utils/assert"
 	"github.com/jackc/pgx/pgtype"
 )
 
@@ -41,9 +42,26 @@ type RaceRow struct {
 	Sponsor *string `db:"sponsor"`
 
 	Metadata []byte `db:"metadata"`
+
+	// only set by ranked searches
+	MatchField *string  `db:"match_field"`
+	MatchValue *string  `db:"match_value"`
+	MatchScore *float64 `db:"match_score"`
 }
 
-func (r *Repository) GetRaceByID(raceID int64) (*RaceRow, error) {
+// fields matched by ranked searches
+const (
+	MATCH_TROPHY  = "trophy"
+	MATCH_FLAG    = "flag"
+	MATCH_LEAGUE  = "league"
+	MATCH_SPONSOR = "sponsor"
+	MATCH_CLUB    = "club"
+)
+
+// MIN_MATCH_SCORE is the minimum word similarity for a race to be returned by a ranked search.
+const MIN_MATCH_SCORE = 0.3
+
+func (r *PostgresRepository) GetRaceByID(ctx context.Context, raceID int64) (*RaceRow, error) {
 	query, args, err := sq.
 		Select("r.id", "r.day", "r.date", "r.gender", "r.type", "r.modality", "r.laps", "r.lanes", "r.cancelled", "r.sponsor", "r.associated_id", "r.metadata",
 			"t.id as trophy_id", "t.name as trophy_name", "r.trophy_edition",
@@ -57,17 +75,32 @@ func (r *Repository) GetRaceByID(raceID int64) (*RaceRow, error) {
 		Where(sq.Eq{"r.id": raceID}).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
-	assert.NoError(err, "building query=%s args=%s", query, args)
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
 
 	var race RaceRow
-	if err = r.db.Get(&race, query, args...); err != nil {
-		return nil, err
+	if err = r.db.GetContext(ctx, &race, query, args...); err != nil {
+		return nil, queryError(err, "loading race=%d", raceID)
 	}
 
 	return &race, nil
 }
 
+// RaceCursor identifies the last race of a page in the search order, the next page starts right after it. Score is
+// only set for ranked searches.
+type RaceCursor struct {
+	Score    *float64
+	Date     time.Time
+	LeagueID *int64
+	ID       int64
+}
+
 type SearchRaceParams struct {
+	After  *RaceCursor // only races after the cursor are returned
+	Limit  uint64      // maximum number of races returned, zero means no limit
+	Ranked bool        // rank the keywords matches by similarity instead of matching substrings
+
 	Keywords      string
 	Year          int16
 	League        string
@@ -80,7 +113,27 @@ type SearchRaceParams struct {
 	ParticipantID int64
 }
 
-func (r *Repository) SearchRaces(filters *SearchRaceParams) ([]RaceRow, error) {
+func (r *PostgresRepository) SearchRaces(ctx context.Context, filters *SearchRaceParams) ([]RaceRow, error) {
+	query, args, err := searchRacesQuery(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var races []RaceRow
+	if err = r.db.SelectContext(ctx, &races, query, args...); err != nil {
+		return nil, queryError(err, "searching races filters=%v", *filters)
+	}
+
+	return races, nil
+}
+
+// searchRacesQuery builds the query of SearchRaces, user values are always bound as arguments.
+func searchRacesQuery(filters *SearchRaceParams) (string, []any, error) {
+	ranked := filters.Ranked && filters.Keywords != ""
+	if ranked && filters.After != nil && filters.After.Score == nil {
+		return "", nil, filterError("ranked search cursor without score")
+	}
+
 	baseSelect := sq.
 		Select("r.id", "r.day", "r.date", "r.gender", "r.type", "r.modality", "r.laps", "r.lanes", "r.cancelled", "r.sponsor",
 			"t.id as trophy_id", "t.name as trophy_name", "r.trophy_edition as trophy_edition",
@@ -92,11 +145,16 @@ func (r *Repository) SearchRaces(filters *SearchRaceParams) ([]RaceRow, error) {
 		LeftJoin("flag f ON f.id = r.flag_id").
 		LeftJoin("league l ON l.id = r.league_id")
 
-	if filters.Keywords != "" {
+	if ranked {
+		baseSelect = baseSelect.
+			Columns("m.field as match_field", "m.value as match_value", "m.score as match_score").
+			JoinClause(rankedMatch(filters.Keywords)).
+			Where(sq.GtOrEq{"m.score": MIN_MATCH_SCORE})
+	} else if filters.Keywords != "" {
 		baseSelect = baseSelect.Where(sq.Or{
-			sq.ILike{"t.name": fmt.Sprint("%", filters.Keywords, "%")},
-			sq.ILike{"f.name": fmt.Sprint("%", filters.Keywords, "%")},
-			sq.ILike{"sponsor": fmt.Sprint("%", filters.Keywords, "%")},
+			sq.ILike{"t.name": likePattern(filters.Keywords)},
+			sq.ILike{"f.name": likePattern(filters.Keywords)},
+			sq.ILike{"r.sponsor": likePattern(filters.Keywords)},
 		})
 	}
 
@@ -105,7 +163,7 @@ func (r *Repository) SearchRaces(filters *SearchRaceParams) ([]RaceRow, error) {
 	}
 
 	if filters.Trophy != "" {
-		baseSelect = baseSelect.Where(sq.ILike{"t.name": fmt.Sprint("%", filters.Trophy, "%")})
+		baseSelect = baseSelect.Where(sq.ILike{"t.name": likePattern(filters.Trophy)})
 	}
 
 	if filters.TrophyID > 0 {
@@ -116,7 +174,7 @@ func (r *Repository) SearchRaces(filters *SearchRaceParams) ([]RaceRow, error) {
 	}
 
 	if filters.Flag != "" {
-		baseSelect = baseSelect.Where(sq.ILike{"f.name": fmt.Sprint("%", filters.Flag, "%")})
+		baseSelect = baseSelect.Where(sq.ILike{"f.name": likePattern(filters.Flag)})
 	}
 
 	if filters.FlagID > 0 {
@@ -128,8 +186,8 @@ func (r *Repository) SearchRaces(filters *SearchRaceParams) ([]RaceRow, error) {
 
 	if filters.League != "" {
 		baseSelect = baseSelect.Where(sq.Or{
-			sq.ILike{"t.name": fmt.Sprint("%", filters.League, "%")},
-			sq.ILike{"t.symbol": fmt.Sprint("%", filters.League, "%")},
+			sq.ILike{"l.name": likePattern(filters.League)},
+			sq.ILike{"l.symbol": likePattern(filters.League)},
 		})
 	}
 
@@ -141,25 +199,101 @@ func (r *Repository) SearchRaces(filters *SearchRaceParams) ([]RaceRow, error) {
 	}
 
 	if filters.Participant != "" {
-		subQ := fmt.Sprintf("EXISTS(SELECT 1 FROM participant p JOIN entity e on p.club_id = e.id WHERE p.race_id = r.id AND e.name ILIKE '%%%s%%')", filters.Participant)
-		baseSelect = baseSelect.Where(subQ)
+		baseSelect = baseSelect.Where(
+			"EXISTS(SELECT 1 FROM participant p JOIN entity e on p.club_id = e.id WHERE p.race_id = r.id AND e.name ILIKE ?)",
+			likePattern(filters.Participant),
+		)
 	}
 
 	if filters.ParticipantID > 0 {
-		subQ := fmt.Sprintf("EXISTS(SELECT 1 FROM participant p WHERE p.race_id = r.id AND p.club_id = %d)", filters.ParticipantID)
-		baseSelect = baseSelect.Where(subQ)
+		baseSelect = baseSelect.Where("EXISTS(SELECT 1 FROM participant p WHERE p.race_id = r.id AND p.club_id = ?)", filters.ParticipantID)
+	}
+
+	if filters.After != nil {
+		if ranked {
+			baseSelect = baseSelect.Where(afterRankedRace(filters.After))
+		} else {
+			baseSelect = baseSelect.Where(afterRace(filters.After))
+		}
+	}
+
+	if filters.Limit > 0 {
+		baseSelect = baseSelect.Limit(filters.Limit)
+	}
+
+	// r.id breaks ties so the order is stable across pages
+	if ranked {
+		baseSelect = baseSelect.OrderBy("m.score DESC", "r.date DESC", "r.id")
+	} else {
+		baseSelect = baseSelect.OrderBy("r.date DESC", "r.league_id", "r.id")
 	}
 
 	query, args, err := baseSelect.
-		OrderBy("date DESC, league_id").
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
-	assert.NoError(err, "building query=%s args=%s", query, args)
+	if err != nil {
+		return "", nil, queryError(err, "building query=%s args=%v", query, args)
+	}
+	return query, args, nil
+}
 
-	var races []RaceRow
-	if err = r.db.Select(&races, query, args...); err != nil {
-		return nil, err
+// afterRace matches the races after the cursor in the (date DESC, league_id, id) order. PostgreSQL sorts NULL league
+// IDs last, so races without a league come after any other race on the same date.
+func afterRace(cursor *RaceCursor) sq.Sqlizer {
+	date := cursor.Date.Format(time.DateOnly)
+
+	sameDate := sq.And{sq.Expr("r.league_id IS NULL"), sq.Gt{"r.id": cursor.ID}}
+	if cursor.LeagueID != nil {
+		sameDate = sq.And{sq.Or{
+			sq.Gt{"r.league_id": *cursor.LeagueID},
+			sq.And{sq.Eq{"r.league_id": *cursor.LeagueID}, sq.Gt{"r.id": cursor.ID}},
+			sq.Expr("r.league_id IS NULL"),
+		}}
 	}
 
-	return races, nil
+	return sq.Or{
+		sq.Expr("r.date < ?::date", date),
+		sq.And{sq.Expr("r.date = ?::date", date), sameDate},
+	}
+}
+
+// rankedMatch joins the best match of the keywords for each race, requires the unaccent and pg_trgm extensions.
+//  1. Collects the searchable names of the race: trophy, flag, league, sponsor and the clubs of its participants.
+//  2. Scores each name with the word similarity of the keywords, ignoring accents.
+//  3. Keeps the best scored name, ties are broken by the field priority so the match is stable.
+func rankedMatch(keywords string) sq.Sqlizer {
+	return sq.Expr(`CROSS JOIN LATERAL (
+		SELECT c.field, c.value, word_similarity(unaccent(?), unaccent(c.value))::double precision AS score
+		FROM (
+			SELECT 1 AS priority, '`+MATCH_TROPHY+`' AS field, t.name AS value
+			UNION ALL SELECT 2, '`+MATCH_FLAG+`', f.name
+			UNION ALL SELECT 3, '`+MATCH_LEAGUE+`', l.name
+			UNION ALL SELECT 4, '`+MATCH_SPONSOR+`', r.sponsor
+			UNION ALL SELECT 5, '`+MATCH_CLUB+`', e.name FROM participant p JOIN entity e ON e.id = p.club_id WHERE p.race_id = r.id
+		) c
+		WHERE c.value IS NOT NULL
+		ORDER BY score DESC, c.priority, c.value
+		LIMIT 1
+	) m`, keywords)
+}
+
+// afterRankedRace matches the races after the cursor in the (score DESC, date DESC, id) order.
+func afterRankedRace(cursor *RaceCursor) sq.Sqlizer {
+	date := cursor.Date.Format(time.DateOnly)
+
+	return sq.Or{
+		sq.Lt{"m.score": *cursor.Score},
+		sq.And{sq.Eq{"m.score": *cursor.Score}, sq.Or{
+			sq.Expr("r.date < ?::date", date),
+			sq.And{sq.Expr("r.date = ?::date", date), sq.Gt{"r.id": cursor.ID}},
+		}},
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// likePattern builds a pattern matching any value containing the given one. LIKE wildcards are escaped, so the value
+// is matched literally.
+func likePattern(value string) string {
+	return "%" + likeEscaper.Replace(value) + "%"
 }
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// likeInputs are user values with quotes and LIKE wildcards, they must be bound as arguments and matched literally.
+var likeInputs = []struct {
+	name    string
+	value   string
+	pattern string // bound LIKE pattern
+	race    int64  // race of newLikeMemory matching the value literally
+}{
+	{"quote", "O'Grove", "%O'Grove%", 1},
+	{"percent", "100%", `%100\%%`, 2},
+	{"underscore", "A_B", `%A\_B%`, 3},
+}
+
+// likeFilters sets each of the LIKE filters of a search.
+var likeFilters = []struct {
+	name string
+	set  func(filters *SearchRaceParams, value string)
+}{
+	{"keywords", func(filters *SearchRaceParams, value string) { filters.Keywords = value }},
+	{"trophy", func(filters *SearchRaceParams, value string) { filters.Trophy = value }},
+	{"flag", func(filters *SearchRaceParams, value string) { filters.Flag = value }},
+	{"league", func(filters *SearchRaceParams, value string) { filters.League = value }},
+	{"participant", func(filters *SearchRaceParams, value string) { filters.Participant = value }},
+}
+
+func TestSearchRacesQueryEscapesLikeFilters(t *testing.T) {
+	for _, input := range likeInputs {
+		for _, filter := range likeFilters {
+			t.Run(input.name+"/"+filter.name, func(t *testing.T) {
+				filters := &SearchRaceParams{}
+				filter.set(filters, input.value)
+
+				query, args, err := searchRacesQuery(filters)
+				if err != nil {
+					t.Fatalf("searchRacesQuery: %v", err)
+				}
+				if strings.Contains(query, input.value) {
+					t.Errorf("query contains %q: %s", input.value, query)
+				}
+				if !slices.Contains(args, any(input.pattern)) {
+					t.Errorf("args=%v, want %q", args, input.pattern)
+				}
+				if slices.Contains(args, any(input.value)) {
+					t.Errorf("args=%v contain the unescaped %q", args, input.value)
+				}
+			})
+		}
+	}
+}
+
+func TestSearchRacesQueryBindsRankedKeywords(t *testing.T) {
+	for _, input := range likeInputs {
+		t.Run(input.name, func(t *testing.T) {
+			// similarity is not a LIKE, the keywords are bound as they are
+			query, args, err := searchRacesQuery(&SearchRaceParams{Keywords: input.value, Ranked: true})
+			if err != nil {
+				t.Fatalf("searchRacesQuery: %v", err)
+			}
+			if strings.Contains(query, input.value) {
+				t.Errorf("query contains %q: %s", input.value, query)
+			}
+			if !slices.Contains(args, any(input.value)) {
+				t.Errorf("args=%v, want %q", args, input.value)
+			}
+		})
+	}
+}
+
+func TestMemorySearchRacesMatchesLiterally(t *testing.T) {
+	m := newLikeMemory(t)
+
+	for _, input := range likeInputs {
+		for _, filter := range likeFilters {
+			t.Run(input.name+"/"+filter.name, func(t *testing.T) {
+				filters := &SearchRaceParams{}
+				filter.set(filters, input.value)
+
+				races, err := m.SearchRaces(context.Background(), filters)
+				if err != nil {
+					t.Fatalf("SearchRaces: %v", err)
+				}
+				ids := make([]int64, len(races))
+				for idx, race := range races {
+					ids[idx] = race.ID
+				}
+				if !slices.Equal(ids, []int64{input.race}) {
+					t.Errorf("races=%v, want [%d]", ids, input.race)
+				}
+			})
+		}
+	}
+}
+
+// newLikeMemory returns a backend where race i has a trophy, flag, league and club named after names[i-1]. The last
+// race matches the values of likeInputs only when their wildcards are not escaped.
+func newLikeMemory(t *testing.T) *MemoryRepository {
+	t.Helper()
+
+	names := []string{"O'GROVE", "100% GALEGA", "A_B", "1000 AXB"}
+	data := &MemoryData{}
+	for idx, name := range names {
+		id := int64(idx + 1)
+		data.Races = append(data.Races, MemoryRace{
+			ID: id, TrophyID: &id, FlagID: &id, LeagueID: &id,
+			Day: 1, Date: "2024-07-06", Gender: "MALE", Category: "ABSOLUT",
+		})
+		data.Participants = append(data.Participants, MemoryParticipant{ID: id, RaceID: id, ClubID: id, Gender: "MALE", Category: "ABSOLUT"})
+		data.Entities = append(data.Entities, MemoryEntity{ID: id, Name: "CLUB " + name, Type: "CLUB"})
+		data.Leagues = append(data.Leagues, MemoryLeague{ID: id, Name: "LIGA " + name, Symbol: fmt.Sprintf("L%d", id)})
+		data.Flags = append(data.Flags, MemoryFlag{ID: id, Name: "BANDEIRA " + name})
+		data.Trophies = append(data.Trophies, MemoryTrophy{ID: id, Name: "TROFEO " + name})
+	}
+
+	m, err := NewMemory(data)
+	if err != nil {
+		t.Fatalf("NewMemory: %v", err)
+	}
+	return m
+}
+
+func TestMemoryGetByIDCancelled(t *testing.T) {
+	m := newLikeMemory(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	getters := map[string]func() error{
+		"club":   func() error { _, err := m.GetClubByID(ctx, 1); return err },
+		"flag":   func() error { _, err := m.GetFlagByID(ctx, 1); return err },
+		"league": func() error { _, err := m.GetLeagueByID(ctx, 1); return err },
+		"trophy": func() error { _, err := m.GetTrophyByID(ctx, 1); return err },
+		"race":   func() error { _, err := m.GetRaceByID(ctx, 1); return err },
+	}
+	for name, get := range getters {
+		t.Run(name, func(t *testing.T) {
+			if err := get(); !errors.Is(err, context.Canceled) {
+				t.Errorf("err=%v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
@@ -1,8 +1,9 @@
 package db
 
 import (
+	"context"
+
 	sq "github.com/Masterminds/squirrel"
-	"github.com/iagocanalejas/rstats/internal/utils/assert"
 )
 
 type TrophyRow struct {
@@ -10,18 +11,20 @@ type TrophyRow struct {
 	Name string `db:"name"`
 }
 
-func (r *Repository) GetTrophyByID(trophyID int64) (*TrophyRow, error) {
+func (r *PostgresRepository) GetTrophyByID(ctx context.Context, trophyID int64) (*TrophyRow, error) {
 	query, args, err := sq.
 		Select("t.id as id", "t.name as name").
 		From("trophy t").
 		Where(sq.Eq{"t.id": trophyID}).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
-	assert.NoError(err, "building query=%s args=%s", query, args)
+	if err != nil {
+		return nil, queryError(err, "building query=%s args=%v", query, args)
+	}
 
 	var trophy TrophyRow
-	if err = r.db.Get(&trophy, query, args...); err != nil {
-		return nil, err
+	if err = r.db.GetContext(ctx, &trophy, query, args...); err != nil {
+		return nil, queryError(err, "loading trophy=%d", trophyID)
 	}
 
 	return &trophy, nil
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iagocanalejas/rstats/internal/types"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+)
+
+// Audit checks the participants of all the races that were not cancelled for structural problems.
+func (s *Service) Audit(ctx context.Context) ([]types.Finding, error) {
+	participants, err := s.db.GetAuditParticipants(ctx)
+	if err != nil {
+		prettylog.Error("error loading participants: %v", err)
+		return nil, err
+	}
+
+	emptyRaceIDs, err := s.db.GetEmptyRaceIDs(ctx)
+	if err != nil {
+		prettylog.Error("error loading races: %v", err)
+		return nil, err
+	}
+
+	return types.NewAudit(participants, emptyRaceIDs), nil
+}
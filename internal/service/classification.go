@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/iagocanalejas/rstats/internal/types"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+)
+
+// PointsTables maps leagues, by ID or symbol, to the points table used in their races.
+type PointsTables map[string]types.PointsTable
+
+// LoadPointsTables reads the points tables from a JSON file:
+//
+//	{"ACT": {"points": [12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1]}, "5": {"points": [10, 8, 6], "rest": 1}}
+func LoadPointsTables(path string) (PointsTables, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading points file=%s: %w", path, err)
+	}
+
+	var tables PointsTables
+	if err = json.Unmarshal(content, &tables); err != nil {
+		return nil, fmt.Errorf("parsing points file=%s: %w", path, err)
+	}
+	return tables, nil
+}
+
+// SetPointsTables configures the points given in league races, races from leagues without a table give no points.
+func (s *Service) SetPointsTables(tables PointsTables) {
+	s.pointsTables = tables
+}
+
+// GetRaceClassification loads a race and classifies its participants per gender and category. Points are assigned
+// when the race league has a points table.
+func (s *Service) GetRaceClassification(ctx context.Context, raceID int64) (*types.Race, []types.Classification, error) {
+	race, err := s.GetRaceByID(ctx, raceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	points, err := s.pointsTableFor(ctx, race.League)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return race, types.NewClassifications(race.Participants, points), nil
+}
+
+// pointsTableFor returns the points table of the league, looking it up by ID and then by symbol.
+func (s *Service) pointsTableFor(ctx context.Context, league *types.League) (*types.PointsTable, error) {
+	if league == nil || len(s.pointsTables) == 0 {
+		return nil, nil
+	}
+
+	if table, ok := s.pointsTables[strconv.FormatInt(league.ID, 10)]; ok {
+		return &table, nil
+	}
+
+	leagues, err := s.leagues(ctx)
+	if err != nil {
+		prettylog.Error("error loading leagues: %v", err)
+		return nil, err
+	}
+	for _, l := range leagues {
+		if l.ID != league.ID {
+			continue
+		}
+		if table, ok := s.pointsTables[l.Symbol]; ok {
+			return &table, nil
+		}
+	}
+	return nil, nil
+}
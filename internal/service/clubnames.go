@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/types"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+)
+
+const MIN_SUGGESTION_SCORE = 0.8 // raw names scoring less than this against another club are not suggested to move
+
+// ReviewClubNames groups the raw names of the participants by club and looks for the ones likely mapped to the wrong
+// club.
+//  1. Raw names used by more than one club are conflicts, the participants of the less used clubs are suggested to
+//     move to the most used one unless their club matches the raw name better.
+//  2. Raw names matching the names of another club, with at least minScore and UNAMBIGUOUS_MARGIN over their current
+//     club, are suggested to move to it.
+//
+// Names are compared by their crew club, without branch letters or sponsors, so branch teams and sponsored crews match
+// their club.
+func (s *Service) ReviewClubNames(ctx context.Context, minScore float64) (*types.ClubNamesReview, error) {
+	rows, err := s.db.GetClubRawNames(ctx)
+	if err != nil {
+		prettylog.Error("error loading club raw names: %v", err)
+		return nil, err
+	}
+
+	clubs := make(map[int64]*types.ClubRawNames)
+	clubIDs := make([]int64, 0)
+	usages := make(map[string][]types.ClubUsage) // raw name to the clubs using it
+	rawNames := make([]string, 0)
+	for _, row := range rows {
+		club, ok := clubs[row.ClubID]
+		if !ok {
+			club = &types.ClubRawNames{Club: types.NewEntityFromDB(&db.EntityRow{ID: row.ClubID, Name: row.ClubName}, nil)}
+			clubs[row.ClubID] = club
+			clubIDs = append(clubIDs, row.ClubID)
+		}
+		club.RawNames = append(club.RawNames, types.RawNameUsage{RawName: row.RawName, Participants: row.Participants, Races: row.Races})
+
+		if _, ok := usages[row.RawName]; !ok {
+			rawNames = append(rawNames, row.RawName)
+		}
+		usages[row.RawName] = append(usages[row.RawName], types.ClubUsage{Club: club.Club, Participants: row.Participants, Races: row.Races})
+	}
+	sort.Slice(clubIDs, func(i, j int) bool { return clubIDs[i] < clubIDs[j] })
+
+	// clubScore is how well the raw name matches the official and other raw names of a club
+	clubScore := func(rawName string, club *types.ClubRawNames) float64 {
+		score := nameScore(crewClub(rawName), crewClub(club.Club.Name))
+		for _, usage := range club.RawNames {
+			if usage.RawName != rawName {
+				score = max(score, nameScore(crewClub(rawName), crewClub(usage.RawName)))
+			}
+		}
+		return score
+	}
+
+	review := &types.ClubNamesReview{
+		Clubs:       make([]types.ClubRawNames, 0, len(clubs)),
+		Conflicts:   make([]types.RawNameConflict, 0),
+		Suggestions: make([]types.MappingSuggestion, 0),
+	}
+	suggested := make(map[string]map[int64]bool) // raw names and clubs already suggested to move
+	suggest := func(suggestion types.MappingSuggestion) {
+		if suggested[suggestion.RawName] == nil {
+			suggested[suggestion.RawName] = make(map[int64]bool)
+		}
+		suggested[suggestion.RawName][suggestion.From.ID] = true
+		review.Suggestions = append(review.Suggestions, suggestion)
+	}
+
+	for _, rawName := range rawNames {
+		if len(usages[rawName]) < 2 {
+			continue
+		}
+
+		conflict := types.RawNameConflict{RawName: rawName, Clubs: usages[rawName]}
+		sort.SliceStable(conflict.Clubs, func(i, j int) bool { return conflict.Clubs[i].Participants > conflict.Clubs[j].Participants })
+		review.Conflicts = append(review.Conflicts, conflict)
+
+		mostUsed := conflict.Clubs[0]
+		score := clubScore(rawName, clubs[mostUsed.Club.ID])
+		for _, usage := range conflict.Clubs[1:] {
+			current := clubScore(rawName, clubs[usage.Club.ID])
+			if usage.Participants < mostUsed.Participants && score >= current {
+				suggest(types.MappingSuggestion{
+					RawName:      rawName,
+					From:         usage.Club,
+					To:           mostUsed.Club,
+					Participants: usage.Participants,
+					Score:        score,
+					CurrentScore: current,
+					Reason:       types.REASON_CONFLICT,
+				})
+			}
+		}
+	}
+
+	for _, clubID := range clubIDs {
+		club := clubs[clubID]
+		for _, usage := range club.RawNames {
+			if suggested[usage.RawName][clubID] {
+				continue
+			}
+
+			current := clubScore(usage.RawName, club)
+			var best *types.ClubRawNames
+			var bestScore float64
+			for _, otherID := range clubIDs {
+				if otherID == clubID {
+					continue
+				}
+				if score := clubScore(usage.RawName, clubs[otherID]); score > bestScore {
+					best, bestScore = clubs[otherID], score
+				}
+			}
+
+			if best != nil && bestScore >= minScore && bestScore-current >= UNAMBIGUOUS_MARGIN {
+				suggest(types.MappingSuggestion{
+					RawName:      usage.RawName,
+					From:         club.Club,
+					To:           best.Club,
+					Participants: usage.Participants,
+					Score:        bestScore,
+					CurrentScore: current,
+					Reason:       types.REASON_CLOSER_NAME,
+				})
+			}
+		}
+	}
+
+	for _, clubID := range clubIDs {
+		club := clubs[clubID]
+		sort.SliceStable(club.RawNames, func(i, j int) bool { return club.RawNames[i].Participants > club.RawNames[j].Participants })
+		review.Clubs = append(review.Clubs, *club)
+	}
+	sort.SliceStable(review.Suggestions, func(i, j int) bool {
+		return review.Suggestions[i].Score-review.Suggestions[i].CurrentScore > review.Suggestions[j].Score-review.Suggestions[j].CurrentScore
+	})
+
+	return review, nil
+}
+
+// crewClub is the club name of the crew, "PUEBLA B (ESTRELLA GALICIA)" becomes "PUEBLA".
+func crewClub(name string) string {
+	return types.ParseCrew(name).Club
+}
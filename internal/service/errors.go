@@ -0,0 +1,17 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+)
+
+// Errors returned by the service, they can be checked with [errors.Is].
+var (
+	ErrNotFound      = db.ErrNotFound
+	ErrInvalidFilter = db.ErrInvalidFilter
+	ErrQuery         = db.ErrQuery
+
+	// ErrAmbiguous is returned when a name matches several clubs, leagues or flags and none of them stands out.
+	ErrAmbiguous = errors.New("ambiguous name")
+)
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/types"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+)
+
+type GetHeadToHeadParams struct {
+	Club            *types.Entity
+	Rival           *types.Entity
+	League          *types.League
+	Flag            *types.Flag
+	Gender          string
+	Category        string
+	Day             int16
+	OnlyLeagueRaces bool
+	ApplyPenalties  bool
+	Latest          int // number of latest meetings loaded with the race name
+}
+
+// GetHeadToHead compares a club with a rival in every race both took part in, only the fastest crew of each club in
+// a race is compared.
+func (s *Service) GetHeadToHead(ctx context.Context, params *GetHeadToHeadParams) (*types.HeadToHead, error) {
+	var leagueID, flagID int64
+	if params.League != nil {
+		leagueID = params.League.ID
+	}
+	if params.Flag != nil {
+		flagID = params.Flag.ID
+	}
+
+	rows, err := s.db.GetHeadToHead(ctx, &db.GetHeadToHeadParams{
+		ClubID:          params.Club.ID,
+		RivalID:         params.Rival.ID,
+		LeagueID:        leagueID,
+		FlagID:          flagID,
+		Gender:          params.Gender,
+		Category:        params.Category,
+		Day:             params.Day,
+		OnlyLeagueRaces: params.OnlyLeagueRaces,
+		ApplyPenalties:  params.ApplyPenalties,
+	})
+	if err != nil {
+		prettylog.Error("error loading head to head: %v", err)
+		return nil, err
+	}
+
+	meetings := make([]types.Meeting, len(rows))
+	for idx, row := range rows {
+		meetings[idx] = *types.NewMeetingFromDB(&row)
+	}
+
+	for idx := max(len(meetings)-params.Latest, 0); idx < len(meetings); idx++ {
+		dbRace, err := s.db.GetRaceByID(ctx, meetings[idx].RaceID)
+		if err != nil {
+			prettylog.Error("error loading race: %v", err)
+			return nil, err
+		}
+		meetings[idx].RaceName = types.NewRaceFromDB(dbRace).Name
+	}
+
+	return types.NewHeadToHead(params.Club, params.Rival, meetings), nil
+}
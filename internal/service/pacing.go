@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/types"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+)
+
+// ParticipantPacing is the pacing of a participant in a race.
+type ParticipantPacing struct {
+	Participant *types.Participant
+	Pacing      *types.Pacing
+}
+
+// GetRacePacing computes the splits and pacing of the participants of a race. Participants without a time for every
+// lap of the race are skipped.
+func (s *Service) GetRacePacing(ctx context.Context, raceID int64) (*types.Race, []ParticipantPacing, error) {
+	race, err := s.GetRaceByID(ctx, raceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if race.Laps == nil {
+		prettylog.Warning("race=%d has no laps", raceID)
+		return race, nil, nil
+	}
+
+	pacings := make([]ParticipantPacing, 0, len(race.Participants))
+	for idx := range race.Participants {
+		participant := &race.Participants[idx]
+		pacing, err := types.NewPacing(participant.Laps, participant.Distance, int(*race.Laps))
+		if err != nil {
+			prettylog.Debug("skipping participant=%d: %v", participant.ID, err)
+			continue
+		}
+		pacings = append(pacings, ParticipantPacing{Participant: participant, Pacing: pacing})
+	}
+
+	return race, pacings, nil
+}
+
+type GetClubPacingParams struct {
+	Club     *types.Entity
+	League   *types.League
+	Gender   string
+	Category string
+	Year     int16
+}
+
+// GetClubPacing computes the pacing profiles of a club, one for each number of laps raced, ordered by the number of
+// races in the profile.
+func (s *Service) GetClubPacing(ctx context.Context, params *GetClubPacingParams) ([]types.PacingProfile, error) {
+	var clubID, leagueID int64
+	if params.Club != nil {
+		clubID = params.Club.ID
+	}
+	if params.League != nil {
+		leagueID = params.League.ID
+	}
+
+	rows, err := s.db.GetParticipantLapsBy(ctx, &db.GetParticipantLapsByParams{
+		ClubID:   clubID,
+		LeagueID: leagueID,
+		Gender:   params.Gender,
+		Category: params.Category,
+		Year:     params.Year,
+	})
+	if err != nil {
+		prettylog.Error("error loading participant laps: %v", err)
+		return nil, err
+	}
+
+	pacingsByLaps := make(map[int][]*types.Pacing)
+	for _, row := range rows {
+		laps, err := types.ParseLaps(row.Laps)
+		if err != nil {
+			prettylog.Error("error loading participant laps: participant=%d: %v", row.ID, err)
+			return nil, err
+		}
+
+		pacing, err := types.NewPacing(laps, row.Distance, int(row.RaceLaps))
+		if err != nil {
+			prettylog.Debug("skipping participant=%d: %v", row.ID, err)
+			continue
+		}
+		pacingsByLaps[int(row.RaceLaps)] = append(pacingsByLaps[int(row.RaceLaps)], pacing)
+	}
+
+	profiles := make([]types.PacingProfile, 0, len(pacingsByLaps))
+	for _, pacings := range pacingsByLaps {
+		profiles = append(profiles, *types.NewPacingProfile(pacings))
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].Races != profiles[j].Races {
+			return profiles[i].Races > profiles[j].Races
+		}
+		return profiles[i].Laps < profiles[j].Laps
+	})
+
+	return profiles, nil
+}
@@ -1,13 +1,19 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/iagocanalejas/rstats/internal/db"
 	"github.com/iagocanalejas/rstats/internal/types"
 	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
 )
 
-func (s *Service) GetParticipantsWithSpeed() ([][]*types.Participant, error) {
-	dbParticipants, err := s.db.GetParticipantsWithSpeed()
+// GetParticipantsWithSpeed loads the classified participants of a category, all of them when empty, grouped by race,
+// gender and category. Groups are in chronological order.
+func (s *Service) GetParticipantsWithSpeed(ctx context.Context, category string) ([][]*types.Participant, error) {
+	dbParticipants, err := s.db.GetParticipantsWithSpeed(ctx, category)
 	if err != nil {
 		prettylog.Error("error loading participants: %v", err)
 		return nil, err
@@ -15,7 +21,11 @@ func (s *Service) GetParticipantsWithSpeed() ([][]*types.Participant, error) {
 
 	participants := make([]*types.Participant, len(dbParticipants))
 	for idx, participant := range dbParticipants {
-		participants[idx] = types.NewParticipantWithSpeedFromDB(&participant)
+		participants[idx], err = types.NewParticipantWithSpeedFromDB(&participant)
+		if err != nil {
+			prettylog.Error("error loading participants: %v", err)
+			return nil, err
+		}
 	}
 
 	return groupParticipants(participants), nil
@@ -51,13 +61,44 @@ type GetYearSpeedsByParams struct {
 	Category        string
 	Day             int16
 	Years           []int
-	BranchTeams     bool
+	Branch          string // a types.BRANCH_* mode or a branch letter, BRANCH_SEPARATED needs GetYearSpeedsByBranch
 	OnlyLeagueRaces bool
 	Normalize       bool
+	ApplyPenalties  bool
+	Excluded        []int64 // participants left out of the normalized speeds
 }
 
 // GetYearSpeedsBy retrieves participant speeds grouped by year.
-func (s *Service) GetYearSpeedsBy(params *GetYearSpeedsByParams) ([]int, *map[int][]float64, error) {
+func (s *Service) GetYearSpeedsBy(ctx context.Context, params *GetYearSpeedsByParams) ([]int, *map[int][]float64, error) {
+	dbParams, err := yearSpeedsParams(params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.db.GetYearSpeedsBy(ctx, dbParams)
+}
+
+// GetDatedSpeedsBy retrieves the participant speeds with the date of their race, filtered like GetYearSpeedsBy.
+func (s *Service) GetDatedSpeedsBy(ctx context.Context, params *GetYearSpeedsByParams) ([]types.DatedSpeed, error) {
+	dbParams, err := yearSpeedsParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.GetDatedSpeedsBy(ctx, dbParams)
+	if err != nil {
+		prettylog.Error("error loading speeds: %v", err)
+		return nil, err
+	}
+
+	speeds := make([]types.DatedSpeed, len(rows))
+	for idx, row := range rows {
+		speeds[idx] = *types.NewDatedSpeedFromDB(&row)
+	}
+	return speeds, nil
+}
+
+func yearSpeedsParams(params *GetYearSpeedsByParams) (*db.GetYearSpeedsByParams, error) {
 	var clubID, leagueID, flagID int64
 	if params.Club != nil {
 		clubID = params.Club.ID
@@ -68,8 +109,12 @@ func (s *Service) GetYearSpeedsBy(params *GetYearSpeedsByParams) ([]int, *map[in
 	if params.Flag != nil {
 		flagID = params.Flag.ID
 	}
+	branch, err := branchFilter(params.Branch)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.db.GetYearSpeedsBy(&db.GetYearSpeedsByParams{
+	return &db.GetYearSpeedsByParams{
 		ClubID:          clubID,
 		LeagueID:        leagueID,
 		FlagID:          flagID,
@@ -77,10 +122,12 @@ func (s *Service) GetYearSpeedsBy(params *GetYearSpeedsByParams) ([]int, *map[in
 		Category:        params.Category,
 		Day:             params.Day,
 		Years:           params.Years,
-		BranchTeams:     params.BranchTeams,
+		Branch:          branch,
 		OnlyLeagueRaces: params.OnlyLeagueRaces,
 		Normalize:       params.Normalize,
-	})
+		ApplyPenalties:  params.ApplyPenalties,
+		Excluded:        params.Excluded,
+	}, nil
 }
 
 type GetNthSpeedsByParams struct {
@@ -91,13 +138,15 @@ type GetNthSpeedsByParams struct {
 	Category        string
 	Day             int16
 	Year            int16
-	BranchTeams     bool
+	Branch          string // a types.BRANCH_* mode but BRANCH_SEPARATED, or a branch letter
 	OnlyLeagueRaces bool
 	Normalize       bool
+	ApplyPenalties  bool
+	Excluded        []int64 // participants left out of the normalized speeds
 }
 
 // GetNthSpeedsBy retrieves the nth fastest speeds for participants based on the provided filtering criteria.
-func (s *Service) GetNthSpeedsBy(params *GetNthSpeedsByParams) ([]float64, error) {
+func (s *Service) GetNthSpeedsBy(ctx context.Context, params *GetNthSpeedsByParams) ([]float64, error) {
 	var clubID, leagueID int64
 	if params.Club != nil {
 		clubID = params.Club.ID
@@ -105,8 +154,12 @@ func (s *Service) GetNthSpeedsBy(params *GetNthSpeedsByParams) ([]float64, error
 	if params.League != nil {
 		leagueID = params.League.ID
 	}
+	branch, err := branchFilter(params.Branch)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.db.GetNthSpeedsBy(&db.GetNthSpeedsByParams{
+	return s.db.GetNthSpeedsBy(ctx, &db.GetNthSpeedsByParams{
 		Index:           params.Index,
 		ClubID:          clubID,
 		LeagueID:        leagueID,
@@ -114,8 +167,78 @@ func (s *Service) GetNthSpeedsBy(params *GetNthSpeedsByParams) ([]float64, error
 		Category:        params.Category,
 		Day:             params.Day,
 		Year:            params.Year,
-		BranchTeams:     params.BranchTeams,
+		Branch:          branch,
 		OnlyLeagueRaces: params.OnlyLeagueRaces,
 		Normalize:       params.Normalize,
+		ApplyPenalties:  params.ApplyPenalties,
+		Excluded:        params.Excluded,
 	})
 }
+
+// BranchSpeeds are the speeds of the crews with the same branch letter grouped by year.
+type BranchSpeeds struct {
+	Branch string // types.MAIN_CREW for the main crews
+	Years  []int
+	Speeds *map[int][]float64
+}
+
+// GetYearSpeedsByBranch retrieves the speeds of the main crews and of each branch letter on their own, so the A and B
+// crews of a club can be compared. Letters without speeds are left out.
+func (s *Service) GetYearSpeedsByBranch(ctx context.Context, params *GetYearSpeedsByParams) ([]BranchSpeeds, error) {
+	letters := append([]string{types.MAIN_CREW}, strings.Split(db.BRANCH_LETTERS, "")...)
+
+	branches := make([]BranchSpeeds, 0, len(letters))
+	for _, letter := range letters {
+		branchParams := *params
+		branchParams.Branch = letter
+
+		years, speeds, err := s.GetYearSpeedsBy(ctx, &branchParams)
+		if err != nil {
+			return nil, err
+		}
+		if len(years) > 0 {
+			branches = append(branches, BranchSpeeds{Branch: letter, Years: years, Speeds: speeds})
+		}
+	}
+	return branches, nil
+}
+
+// ClubSpeeds are the speeds of a club grouped by year.
+type ClubSpeeds struct {
+	Club   *types.Entity
+	Years  []int
+	Speeds *map[int][]float64
+}
+
+// GetYearSpeedsByClubs retrieves the speeds of each club on its own, so a club can be compared with its rivals. The
+// params club is ignored and clubs without speeds are kept with no years.
+func (s *Service) GetYearSpeedsByClubs(ctx context.Context, params *GetYearSpeedsByParams, clubs []*types.Entity) ([]ClubSpeeds, error) {
+	speeds := make([]ClubSpeeds, 0, len(clubs))
+	for _, club := range clubs {
+		clubParams := *params
+		clubParams.Club = club
+
+		years, clubSpeeds, err := s.GetYearSpeedsBy(ctx, &clubParams)
+		if err != nil {
+			return nil, err
+		}
+		speeds = append(speeds, ClubSpeeds{Club: club, Years: years, Speeds: clubSpeeds})
+	}
+	return speeds, nil
+}
+
+// branchFilter converts a branch mode, or a single branch letter, into the db branch filter.
+func branchFilter(mode string) (string, error) {
+	switch mode {
+	case types.BRANCH_ALL:
+		return db.BRANCH_ALL, nil
+	case types.BRANCH_MAIN, types.MAIN_CREW:
+		return db.BRANCH_MAIN, nil
+	case types.BRANCH_ONLY:
+		return db.BRANCH_TEAMS, nil
+	case types.BRANCH_SEPARATED:
+		return "", fmt.Errorf("%w: branch=%s needs the speeds of each branch, see GetYearSpeedsByBranch", ErrInvalidFilter, mode)
+	}
+	// a single branch letter is validated by the db
+	return mode, nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/iagocanalejas/rstats/internal/types"
+)
+
+func TestGetYearSpeedsBy(t *testing.T) {
+	s := newTestService(t)
+
+	// every crew of the fixture rows 5556m
+	speed := func(d time.Duration) float64 { return 5556 / d.Seconds() * 3.6 }
+
+	tests := []struct {
+		name   string
+		params GetYearSpeedsByParams
+		want   map[int][]float64
+	}{
+		{
+			// disqualified, guest and retired crews are left out
+			name:   "all crews",
+			params: GetYearSpeedsByParams{},
+			want: map[int][]float64{
+				2023: {speed(20 * time.Minute), speed(20*time.Minute + 30*time.Second), speed(21 * time.Minute)},
+				2024: {speed(19*time.Minute + 30*time.Second), speed(20*time.Minute + 10*time.Second)},
+			},
+		},
+		{
+			name:   "club",
+			params: GetYearSpeedsByParams{Club: &types.Entity{ID: 1}},
+			want: map[int][]float64{
+				2023: {speed(20 * time.Minute), speed(20*time.Minute + 30*time.Second)},
+				2024: {speed(19*time.Minute + 30*time.Second)},
+			},
+		},
+		{
+			name:   "league",
+			params: GetYearSpeedsByParams{League: &types.League{ID: 1}},
+			want: map[int][]float64{
+				2023: {speed(20 * time.Minute), speed(21 * time.Minute)},
+				2024: {speed(19*time.Minute + 30*time.Second)},
+			},
+		},
+		{
+			name:   "years",
+			params: GetYearSpeedsByParams{Years: []int{2024}},
+			want: map[int][]float64{
+				2024: {speed(19*time.Minute + 30*time.Second), speed(20*time.Minute + 10*time.Second)},
+			},
+		},
+		{
+			name:   "other category",
+			params: GetYearSpeedsByParams{Category: "VETERAN"},
+			want:   map[int][]float64{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := test.params
+			params.Gender, params.Branch = "MALE", types.BRANCH_ALL
+			if params.Category == "" {
+				params.Category = "ABSOLUT"
+			}
+
+			years, speeds, err := s.GetYearSpeedsBy(context.Background(), &params)
+			if err != nil {
+				t.Fatalf("GetYearSpeedsBy: %v", err)
+			}
+
+			wantYears := make([]int, 0, len(test.want))
+			for year := range test.want {
+				wantYears = append(wantYears, year)
+			}
+			slices.Sort(wantYears)
+			if !slices.Equal(years, wantYears) {
+				t.Fatalf("years=%v, want %v", years, wantYears)
+			}
+			for _, year := range years {
+				got, want := (*speeds)[year], test.want[year]
+				if !slices.EqualFunc(got, want, func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }) {
+					t.Errorf("year=%d speeds=%v, want %v", year, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetYearSpeedsByInvalidFilters(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		name   string
+		params GetYearSpeedsByParams
+	}{
+		{"no gender", GetYearSpeedsByParams{Category: "ABSOLUT", Branch: types.BRANCH_ALL}},
+		{"no category", GetYearSpeedsByParams{Gender: "MALE", Branch: types.BRANCH_ALL}},
+		{"invalid day", GetYearSpeedsByParams{Gender: "MALE", Category: "ABSOLUT", Day: 3, Branch: types.BRANCH_ALL}},
+		{"separated branches", GetYearSpeedsByParams{Gender: "MALE", Category: "ABSOLUT", Branch: types.BRANCH_SEPARATED}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := s.GetYearSpeedsBy(context.Background(), &test.params); !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("err=%v, want %v", err, ErrInvalidFilter)
+			}
+		})
+	}
+}
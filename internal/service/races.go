@@ -1,58 +1,147 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/iagocanalejas/rstats/internal/db"
 	"github.com/iagocanalejas/rstats/internal/types"
-	"github.com/iagocanalejas/rstats/internal/utils/assert"
 	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
 )
 
-func (s *Service) GetRaceByID(raceID int64) (*types.Race, error) {
-	dbRace, err := s.db.GetRaceByID(raceID)
+func (s *Service) GetRaceByID(ctx context.Context, raceID int64) (*types.Race, error) {
+	dbRace, err := s.db.GetRaceByID(ctx, raceID)
 	if err != nil {
 		prettylog.Error("error loading race: %v", err)
 		return nil, err
 	}
 
-	dbParticipants, err := s.db.GetParticipantsByRaceID(raceID)
+	dbParticipants, err := s.db.GetParticipantsByRaceID(ctx, raceID)
 	if err != nil {
 		prettylog.Error("error loading participants: %v", err)
 		return nil, err
 	}
 
+	dbPenalties, err := s.db.GetPenaltiesByRaceID(ctx, raceID)
+	if err != nil {
+		prettylog.Error("error loading penalties: %v", err)
+		return nil, err
+	}
+
+	penalties := make(map[int64][]types.Penalty)
+	for _, penalty := range dbPenalties {
+		penalties[penalty.ParticipantID] = append(penalties[penalty.ParticipantID], *types.NewPenaltyFromDB(&penalty))
+	}
+
+	r := types.NewRaceFromDB(dbRace)
+
+	expectedLaps := 0
+	if r.Laps != nil {
+		expectedLaps = int(*r.Laps)
+	}
+
 	// TODO: implement lap normalizations
 	ps := make([]types.Participant, len(dbParticipants))
 	for idx, participant := range dbParticipants {
-		ps[idx] = *types.NewParticipantFromDB(&participant)
-	}
+		p, err := types.NewParticipantFromDB(&participant)
+		if err != nil {
+			prettylog.Error("error loading participants: %v", err)
+			return nil, err
+		}
+		p.Penalties = penalties[participant.ID]
 
-	r := types.NewRaceFromDB(dbRace)
+		// incomplete laps are expected for retired crews, so they are reported but kept
+		if len(p.Laps) > 0 {
+			if err := p.Laps.Validate(expectedLaps); err != nil {
+				prettylog.Warning("race=%d participant=%d: %v", raceID, p.ID, err)
+			}
+		}
+		ps[idx] = *p
+	}
 	r.Participants = ps
 
 	return r, nil
 }
 
-func (s *Service) SearchRaces(keywords string) ([]types.Race, error) {
+// RaceCursor points to the last race of a [RacesPage], it is used to request the following page. Score is only set
+// for ranked searches.
+type RaceCursor struct {
+	Score    *float64
+	Date     time.Time
+	LeagueID *int64
+	ID       int64
+
+	ParticipantID int64 // club the participant filter resolved to in the first page, zero when it is a substring
+}
+
+// RacesPage is a page of search results ordered by date (newest first) and league, or by relevance for ranked
+// searches. Next is nil on the last page.
+type RacesPage struct {
+	Races []types.Race
+	Next  *RaceCursor
+}
+
+// SearchRaces returns the page of races matching the keywords that follows the given cursor, a nil cursor returns the
+// first page. A limit of zero returns all the races in a single page.
+//
+// Free keywords are matched as substrings ordered by date. The "mode:ranked" filter ranks them by their similarity with
+// the trophy, flag, league, sponsor and club names ignoring accents and word order, which needs the unaccent and
+// pg_trgm extensions.
+func (s *Service) SearchRaces(ctx context.Context, keywords string, after *RaceCursor, limit int) (*RacesPage, error) {
 	// filters should be sent in <key>:<value>, ...
 	filters, err := buildFilters(keywords)
-	assert.NoError(err, "building filters keywords=%s", keywords)
+	if err != nil {
+		prettylog.Error("error building filters: %v", err)
+		return nil, err
+	}
+
+	// an unambiguous participant name is narrowed to its club, otherwise it is matched as a substring. The club is
+	// resolved in the first page and carried by the cursor so every page uses the same filter.
+	var participantID int64
+	if filters.Participant != "" && filters.ParticipantID == 0 {
+		if after != nil {
+			participantID = after.ParticipantID
+		} else if candidates, err := s.FindClubs(ctx, filters.Participant); err == nil {
+			if candidate, err := pickCandidate("club", filters.Participant, candidates); err == nil {
+				prettylog.Debug("participant=%s resolved to club=%d", filters.Participant, candidate.ID)
+				participantID = candidate.ID
+			}
+		}
+	}
+	if participantID > 0 {
+		filters.Participant, filters.ParticipantID = "", participantID
+	}
+
+	if after != nil {
+		filters.After = &db.RaceCursor{Score: after.Score, Date: after.Date, LeagueID: after.LeagueID, ID: after.ID}
+	}
+	if limit > 0 {
+		// fetch an extra race to know if there is a next page
+		filters.Limit = uint64(limit) + 1
+	}
 
 	prettylog.Debug("searching races with filters=%v", *filters)
-	flatRaces, err := s.db.SearchRaces(filters)
+	flatRaces, err := s.db.SearchRaces(ctx, filters)
 	if err != nil {
 		prettylog.Error("error searching races: %v", err)
 		return nil, err
 	}
 
-	rs := make([]types.Race, len(flatRaces))
+	page := &RacesPage{}
+	if limit > 0 && len(flatRaces) > limit {
+		flatRaces = flatRaces[:limit]
+		last := flatRaces[limit-1]
+		page.Next = &RaceCursor{Score: last.MatchScore, Date: last.Date.Time, LeagueID: last.LeagueID, ID: last.ID, ParticipantID: participantID}
+	}
+
+	page.Races = make([]types.Race, len(flatRaces))
 	for idx, race := range flatRaces {
-		rs[idx] = *types.NewRaceFromDB(&race)
+		page.Races[idx] = *types.NewRaceFromDB(&race)
 	}
-	return rs, nil
+	return page, nil
 }
 
 func buildFilters(k string) (*db.SearchRaceParams, error) {
@@ -65,16 +154,25 @@ func buildFilters(k string) (*db.SearchRaceParams, error) {
 
 		keyValue := strings.Split(part, ":")
 		if len(keyValue) != 2 {
-			return nil, fmt.Errorf("invalid filter format: %s", part)
+			return nil, fmt.Errorf("%w: invalid format %s", ErrInvalidFilter, part)
 		}
 
 		key := strings.TrimSpace(keyValue[0])
 		value := strings.TrimSpace(keyValue[1])
 		switch key {
+		case "mode":
+			switch value {
+			case "ranked":
+				filter.Ranked = true
+			case "plain":
+				filter.Ranked = false
+			default:
+				return nil, fmt.Errorf("%w: %s expects ranked or plain, got %s", ErrInvalidFilter, key, value)
+			}
 		case "year":
 			year, err := strconv.ParseInt(value, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("error parsing number from: %s", value)
+				return nil, fmt.Errorf("%w: %s expects a number, got %s", ErrInvalidFilter, key, value)
 			}
 			filter.Year = int16(year)
 		case "flag":
@@ -82,7 +180,7 @@ func buildFilters(k string) (*db.SearchRaceParams, error) {
 		case "flag_id":
 			flagID, err := strconv.ParseInt(value, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("error parsing number from: %s", value)
+				return nil, fmt.Errorf("%w: %s expects a number, got %s", ErrInvalidFilter, key, value)
 			}
 			filter.FlagID = flagID
 		case "trophy":
@@ -90,7 +188,7 @@ func buildFilters(k string) (*db.SearchRaceParams, error) {
 		case "trophy_id":
 			trophyID, err := strconv.ParseInt(value, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("error parsing number from: %s", value)
+				return nil, fmt.Errorf("%w: %s expects a number, got %s", ErrInvalidFilter, key, value)
 			}
 			filter.TrophyID = trophyID
 		case "league":
@@ -98,7 +196,7 @@ func buildFilters(k string) (*db.SearchRaceParams, error) {
 		case "league_id":
 			leagueID, err := strconv.ParseInt(value, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("error parsing number from: %s", value)
+				return nil, fmt.Errorf("%w: %s expects a number, got %s", ErrInvalidFilter, key, value)
 			}
 			filter.LeagueID = leagueID
 		case "participant":
@@ -106,11 +204,11 @@ func buildFilters(k string) (*db.SearchRaceParams, error) {
 		case "participant_id":
 			participantID, err := strconv.ParseInt(value, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("error parsing number from: %s", value)
+				return nil, fmt.Errorf("%w: %s expects a number, got %s", ErrInvalidFilter, key, value)
 			}
 			filter.ParticipantID = participantID
 		default:
-			return nil, fmt.Errorf("unknown filter key: %s", key)
+			return nil, fmt.Errorf("%w: unknown key %s", ErrInvalidFilter, key)
 		}
 	}
 	return &filter, nil
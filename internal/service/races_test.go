@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/types"
+)
+
+func TestGetRaceByID(t *testing.T) {
+	s := newTestService(t)
+
+	race, err := s.GetRaceByID(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetRaceByID: %v", err)
+	}
+	if race.Name != "X - TROFEO DE BUEU" {
+		t.Errorf("name=%q, want %q", race.Name, "X - TROFEO DE BUEU")
+	}
+	if race.Trophy == nil || race.Trophy.ID != 1 || race.League == nil || race.League.ID != 1 || race.Flag != nil {
+		t.Errorf("trophy=%v flag=%v league=%v", race.Trophy, race.Flag, race.League)
+	}
+
+	// ordered by time with the disqualified crews last
+	if ids := participantIDs(race.Participants); !slices.Equal(ids, []int64{11, 12, 13}) {
+		t.Errorf("participants=%v, want [11 12 13]", ids)
+	}
+	last := race.Participants[2]
+	if !last.IsDisqualified || len(last.Penalties) != 1 || !last.Penalties[0].Disqualification {
+		t.Errorf("participant=%d disqualified=%t penalties=%v", last.ID, last.IsDisqualified, last.Penalties)
+	}
+	if got := race.Participants[0].Laps[3]; got != 20*time.Minute {
+		t.Errorf("last lap=%s, want 20m0s", got)
+	}
+}
+
+func TestGetRaceByIDNotFound(t *testing.T) {
+	s := newTestService(t)
+
+	if _, err := s.GetRaceByID(context.Background(), 99); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("err=%v, want %v", err, db.ErrNotFound)
+	}
+}
+
+func TestSearchRaces(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		name     string
+		keywords string
+		want     []int64
+	}{
+		{"all by date and league", "", []int64{4, 3, 1, 2}},
+		{"trophy substring", "bueu", []int64{3, 1}},
+		{"flag substring ignores case", "Vigo", []int64{4, 2}},
+		{"sponsor substring", "moaña", []int64{4}},
+		{"no match", "ondarroa", []int64{}},
+		{"year", "year:2023", []int64{1, 2}},
+		{"league symbol", "league:LGT", []int64{3, 1}},
+		{"flag and year", "flag:vigo, year:2024", []int64{4}},
+		{"trophy id", "trophy_id:1", []int64{3, 1}},
+		{"participant name", "participant:mecos", []int64{4, 1}},
+		{"participant substring", "participant:a", []int64{3, 1, 2}},
+		{"participant id", "participant_id:2", []int64{3, 1, 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := s.SearchRaces(context.Background(), test.keywords, nil, 0)
+			if err != nil {
+				t.Fatalf("SearchRaces(%q): %v", test.keywords, err)
+			}
+			if ids := raceIDs(page.Races); !slices.Equal(ids, test.want) {
+				t.Errorf("SearchRaces(%q)=%v, want %v", test.keywords, ids, test.want)
+			}
+			if page.Next != nil {
+				t.Errorf("SearchRaces(%q) has a next page without limit", test.keywords)
+			}
+		})
+	}
+}
+
+func TestSearchRacesPages(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		name     string
+		keywords string
+		limit    int
+		want     [][]int64
+	}{
+		{"single races", "", 1, [][]int64{{4}, {3}, {1}, {2}}},
+		{"last page is short", "", 3, [][]int64{{4, 3, 1}, {2}}},
+		{"exact pages", "", 2, [][]int64{{4, 3}, {1, 2}}},
+		{"resolved participant", "participant:mecos", 1, [][]int64{{4}, {1}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var after *RaceCursor
+			for idx, want := range test.want {
+				page, err := s.SearchRaces(context.Background(), test.keywords, after, test.limit)
+				if err != nil {
+					t.Fatalf("page=%d: %v", idx, err)
+				}
+				if ids := raceIDs(page.Races); !slices.Equal(ids, want) {
+					t.Errorf("page=%d races=%v, want %v", idx, ids, want)
+				}
+				if last := idx == len(test.want)-1; last != (page.Next == nil) {
+					t.Fatalf("page=%d next=%v, want last=%t", idx, page.Next, last)
+				}
+				after = page.Next
+			}
+		})
+	}
+}
+
+func TestSearchRacesInvalidFilters(t *testing.T) {
+	s := newTestService(t)
+
+	for _, keywords := range []string{"year:abc", "unknown:1", "a:b:c", "mode:fuzzy"} {
+		if _, err := s.SearchRaces(context.Background(), keywords, nil, 0); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("SearchRaces(%q) err=%v, want %v", keywords, err, ErrInvalidFilter)
+		}
+	}
+}
+
+func raceIDs(races []types.Race) []int64 {
+	ids := make([]int64, len(races))
+	for idx, race := range races {
+		ids[idx] = race.ID
+	}
+	return ids
+}
+
+func participantIDs(participants []types.Participant) []int64 {
+	ids := make([]int64, len(participants))
+	for idx, participant := range participants {
+		ids[idx] = participant.ID
+	}
+	return ids
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iagocanalejas/rstats/internal/types"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+)
+
+type GetClubRatingsParams struct {
+	System   string // one of [types.RATING_ELO] or [types.RATING_GLICKO2]
+	Gender   string
+	Category string
+}
+
+// GetClubRatings rates the clubs of a gender and category replaying all their races in chronological order. League
+// and flag races are rated together so the strength of the clubs propagates across leagues.
+func (s *Service) GetClubRatings(ctx context.Context, params *GetClubRatingsParams) (*types.Ratings, error) {
+	ratings, err := types.NewRatings(params.System, params.Gender, params.Category)
+	if err != nil {
+		prettylog.Error("error building ratings: %v", err)
+		return nil, err
+	}
+
+	groups, err := s.GetParticipantsWithSpeed(ctx, params.Category)
+	if err != nil {
+		return nil, err
+	}
+
+	races := 0
+	for _, group := range groups {
+		if group[0].Gender != params.Gender {
+			continue
+		}
+		ratings.AddRace(group[0].RaceID, *group[0].RaceDate, group)
+		races++
+	}
+	prettylog.Debug("rated %d races for gender=%s category=%s", races, params.Gender, params.Category)
+
+	return ratings, nil
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/types"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	utils "github.com/iagocanalejas/rstats/internal/utils/strings"
+)
+
+const (
+	MIN_CANDIDATE_SCORE = 0.5 // candidates scoring less than this are discarded
+	UNAMBIGUOUS_MARGIN  = 0.2 // score difference with the runner-up needed to auto-pick a candidate
+)
+
+// Candidate is a club, league or flag matching a free-text name. Matched is the name (official, raw or symbol) that
+// scored best and Score goes from 0 to 1, 1 being reserved for exact matches.
+type Candidate struct {
+	ID      int64
+	Name    string
+	Matched string
+	Score   float64
+}
+
+// nameCache keeps the names used by the resolver, they are loaded once as they rarely change.
+type nameCache struct {
+	mu      sync.Mutex
+	clubs   []db.ClubNamesRow
+	leagues []db.LeagueRow
+	flags   []db.FlagRow
+}
+
+// FindClubs returns the clubs matching the name ordered by score, both the official names and the raw names used in
+// the races are considered.
+func (s *Service) FindClubs(ctx context.Context, name string) ([]Candidate, error) {
+	clubs, err := s.clubNames(ctx)
+	if err != nil {
+		prettylog.Error("error loading club names: %v", err)
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0)
+	for _, club := range clubs {
+		names := append([]string{club.Name}, club.RawNames...)
+		if candidate, ok := bestCandidate(name, club.ID, club.Name, names); ok {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return sortCandidates(candidates), nil
+}
+
+// FindLeagues returns the leagues matching the name or symbol ordered by score.
+func (s *Service) FindLeagues(ctx context.Context, name string) ([]Candidate, error) {
+	leagues, err := s.leagues(ctx)
+	if err != nil {
+		prettylog.Error("error loading leagues: %v", err)
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0)
+	for _, league := range leagues {
+		if candidate, ok := bestCandidate(name, league.ID, league.Name, []string{league.Name, league.Symbol}); ok {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return sortCandidates(candidates), nil
+}
+
+// FindFlags returns the flags matching the name ordered by score.
+func (s *Service) FindFlags(ctx context.Context, name string) ([]Candidate, error) {
+	flags, err := s.flags(ctx)
+	if err != nil {
+		prettylog.Error("error loading flags: %v", err)
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0)
+	for _, flag := range flags {
+		if candidate, ok := bestCandidate(name, flag.ID, flag.Name, []string{flag.Name}); ok {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return sortCandidates(candidates), nil
+}
+
+// ResolveClub returns the club identified by value, which can be an ID or a name. Names must match a single club
+// unambiguously, see [pickCandidate].
+func (s *Service) ResolveClub(ctx context.Context, value string) (*types.Entity, error) {
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return s.GetClubByID(ctx, id)
+	}
+
+	candidates, err := s.FindClubs(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := pickCandidate("club", value, candidates)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetClubByID(ctx, candidate.ID)
+}
+
+// ResolveLeague returns the league identified by value, which can be an ID, a name or a symbol.
+func (s *Service) ResolveLeague(ctx context.Context, value string) (*types.League, error) {
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return s.GetLeagueByID(ctx, id)
+	}
+
+	candidates, err := s.FindLeagues(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := pickCandidate("league", value, candidates)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetLeagueByID(ctx, candidate.ID)
+}
+
+// ResolveFlag returns the flag identified by value, which can be an ID or a name.
+func (s *Service) ResolveFlag(ctx context.Context, value string) (*types.Flag, error) {
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return s.GetFlagByID(ctx, id)
+	}
+
+	candidates, err := s.FindFlags(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := pickCandidate("flag", value, candidates)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetFlagByID(ctx, candidate.ID)
+}
+
+// pickCandidate returns the best candidate when the match is unambiguous:
+//  1. It is the only candidate.
+//  2. It is the only exact match.
+//  3. Its score is at least UNAMBIGUOUS_MARGIN over the runner-up.
+func pickCandidate(kind string, value string, candidates []Candidate) (*Candidate, error) {
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: no %s matches %s", ErrNotFound, kind, value)
+	}
+
+	best := candidates[0]
+	if len(candidates) == 1 || best.Score-candidates[1].Score >= UNAMBIGUOUS_MARGIN || (best.Score == 1 && candidates[1].Score < 1) {
+		return &best, nil
+	}
+
+	options := make([]string, 0, 5)
+	for _, candidate := range candidates[:min(len(candidates), 5)] {
+		options = append(options, fmt.Sprintf("%d=%s", candidate.ID, candidate.Name))
+	}
+	return nil, fmt.Errorf("%w: %s %s matches %s", ErrAmbiguous, kind, value, strings.Join(options, ", "))
+}
+
+// bestCandidate scores all the names of an item and keeps the best one.
+func bestCandidate(query string, id int64, name string, names []string) (Candidate, bool) {
+	candidate := Candidate{ID: id, Name: name}
+	for _, n := range names {
+		if score := nameScore(query, n); score > candidate.Score {
+			candidate.Score = score
+			candidate.Matched = n
+		}
+	}
+	return candidate, candidate.Score >= MIN_CANDIDATE_SCORE
+}
+
+func sortCandidates(candidates []Candidate) []Candidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	return candidates
+}
+
+// nameScore compares two names ignoring case, accents and punctuation. Exact matches score 1, otherwise the best of
+// the word similarity (words contained in the name) and the edit distance (typos) is used, capped below 1.
+func nameScore(query, name string) float64 {
+	query, name = normalizeName(query), normalizeName(name)
+	if query == "" || name == "" {
+		return 0
+	}
+	if query == name {
+		return 1
+	}
+
+	distance := utils.Levenshtein(query, name)
+	score := max(utils.WordSimilarity(query, name), 1-float64(distance)/float64(max(len(query), len(name))))
+	return min(score, 0.99)
+}
+
+func normalizeName(name string) string {
+	name = strings.ToUpper(utils.Unaccent(name))
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func (s *Service) clubNames(ctx context.Context) ([]db.ClubNamesRow, error) {
+	s.names.mu.Lock()
+	defer s.names.mu.Unlock()
+
+	if s.names.clubs == nil {
+		clubs, err := s.db.GetClubNames(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.names.clubs = clubs
+	}
+	return s.names.clubs, nil
+}
+
+func (s *Service) leagues(ctx context.Context) ([]db.LeagueRow, error) {
+	s.names.mu.Lock()
+	defer s.names.mu.Unlock()
+
+	if s.names.leagues == nil {
+		leagues, err := s.db.GetLeagues(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.names.leagues = leagues
+	}
+	return s.names.leagues, nil
+}
+
+func (s *Service) flags(ctx context.Context) ([]db.FlagRow, error) {
+	s.names.mu.Lock()
+	defer s.names.mu.Unlock()
+
+	if s.names.flags == nil {
+		flags, err := s.db.GetFlags(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.names.flags = flags
+	}
+	return s.names.flags, nil
+}
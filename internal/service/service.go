@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/iagocanalejas/rstats/internal/db"
 	"github.com/iagocanalejas/rstats/internal/types"
 	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
@@ -8,11 +10,24 @@ import (
 
 type Service struct {
 	db db.Repository
+
+	names        nameCache
+	pointsTables PointsTables
+}
+
+// Init builds a service over the repository described by the config, see [db.New].
+func Init(ctx context.Context, config *db.Config) (*Service, error) {
+	repository, err := db.New(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return New(repository), nil
 }
 
-func Init() *Service {
+// New builds a service over the given repository.
+func New(repository db.Repository) *Service {
 	return &Service{
-		db: db.New(),
+		db: repository,
 	}
 }
 
@@ -21,8 +36,8 @@ func Static() *Service {
 	return &Service{}
 }
 
-func (s *Service) GetLeagueByID(leagueID int64) (*types.League, error) {
-	dbLeague, err := s.db.GetLeagueByID(leagueID)
+func (s *Service) GetLeagueByID(ctx context.Context, leagueID int64) (*types.League, error) {
+	dbLeague, err := s.db.GetLeagueByID(ctx, leagueID)
 	if err != nil {
 		prettylog.Error("error loading league: %v", err)
 		return nil, err
@@ -32,8 +47,8 @@ func (s *Service) GetLeagueByID(leagueID int64) (*types.League, error) {
 	return l, nil
 }
 
-func (s *Service) GetFlagByID(flagID int64) (*types.Flag, error) {
-	dbFlag, err := s.db.GetFlagByID(flagID)
+func (s *Service) GetFlagByID(ctx context.Context, flagID int64) (*types.Flag, error) {
+	dbFlag, err := s.db.GetFlagByID(ctx, flagID)
 	if err != nil {
 		prettylog.Error("error loading flag: %v", err)
 		return nil, err
@@ -43,8 +58,8 @@ func (s *Service) GetFlagByID(flagID int64) (*types.Flag, error) {
 	return f, nil
 }
 
-func (s *Service) GetClubByID(clubID int64) (*types.Entity, error) {
-	dbClub, err := s.db.GetClubByID(clubID)
+func (s *Service) GetClubByID(ctx context.Context, clubID int64) (*types.Entity, error) {
+	dbClub, err := s.db.GetClubByID(ctx, clubID)
 	if err != nil {
 		prettylog.Error("error loading club: %v", err)
 		return nil, err
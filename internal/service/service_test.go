@@ -0,0 +1,17 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+)
+
+// newTestService returns a service over the memory backend loaded with testdata/races.json.
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	repository, err := db.NewMemoryFromFile("testdata/races.json")
+	if err != nil {
+		t.Fatalf("loading memory database: %v", err)
+	}
+	return New(repository)
+}
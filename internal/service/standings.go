@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/iagocanalejas/rstats/internal/types"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+)
+
+// GetLeagueStandings aggregates the classifications of the league races of a season into its standings.
+//  1. Loads the races of the league in the year, ordered by date and skipping the cancelled ones.
+//  2. Classifies each race with the league points table, or with [types.DescendingPointsTable] when there is none.
+//  3. Sums the points of each club for the given gender and category, discarding the worst results as configured.
+//
+// The standings after each round are kept in the progression.
+func (s *Service) GetLeagueStandings(ctx context.Context, leagueID int64, year int, gender, category string) (*types.Standings, error) {
+	league, err := s.GetLeagueByID(ctx, leagueID)
+	if err != nil {
+		return nil, err
+	}
+
+	dbRaces, err := s.db.SearchRaces(ctx, &db.SearchRaceParams{LeagueID: leagueID, Year: int16(year)})
+	if err != nil {
+		prettylog.Error("error loading races: %v", err)
+		return nil, err
+	}
+	sort.SliceStable(dbRaces, func(i, j int) bool {
+		if !dbRaces[i].Date.Time.Equal(dbRaces[j].Date.Time) {
+			return dbRaces[i].Date.Time.Before(dbRaces[j].Date.Time)
+		}
+		return dbRaces[i].ID < dbRaces[j].ID
+	})
+
+	points, err := s.pointsTableFor(ctx, league)
+	if err != nil {
+		return nil, err
+	}
+	var discards int
+	if points != nil {
+		discards = points.Discards
+	}
+
+	standings := &types.Standings{League: league, Year: year, Gender: gender, Category: category}
+	classifications := make([]*types.Classification, 0, len(dbRaces))
+	for _, dbRace := range dbRaces {
+		if dbRace.IsCancelled {
+			prettylog.Debug("skipping cancelled race=%d", dbRace.ID)
+			continue
+		}
+
+		race, err := s.GetRaceByID(ctx, dbRace.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		classification := roundClassification(race, points, gender, category)
+		if classification == nil {
+			prettylog.Debug("skipping race=%d without participants for gender=%s category=%s", race.ID, gender, category)
+			continue
+		}
+
+		// participants are not needed once classified and only make the standings heavier
+		race.Participants = nil
+		standings.Rounds = append(standings.Rounds, *race)
+		classifications = append(classifications, classification)
+	}
+
+	standings.Progression = make([][]types.StandingsEntry, len(standings.Rounds))
+	for round := range standings.Rounds {
+		standings.Progression[round] = types.NewStandings(standings.Rounds[:round+1], classifications[:round+1], discards)
+	}
+	if len(standings.Progression) > 0 {
+		standings.Entries = standings.Progression[len(standings.Progression)-1]
+	}
+
+	return standings, nil
+}
+
+// roundClassification classifies the participants of the race matching gender and category, nil when there are none.
+func roundClassification(race *types.Race, points *types.PointsTable, gender, category string) *types.Classification {
+	participants := make([]types.Participant, 0, len(race.Participants))
+	for _, participant := range race.Participants {
+		if participant.Gender == gender && participant.Category == category {
+			participants = append(participants, participant)
+		}
+	}
+	if len(participants) == 0 {
+		return nil
+	}
+
+	if points == nil {
+		classified := 0
+		for _, classification := range types.NewClassifications(participants, nil) {
+			for _, entry := range classification.Entries {
+				if entry.Status == types.STATUS_CLASSIFIED {
+					classified++
+				}
+			}
+		}
+		points = types.DescendingPointsTable(classified)
+	}
+
+	return &types.NewClassifications(participants, points)[0]
+}
@@ -0,0 +1,251 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+)
+
+const (
+	// AUDIT SEVERITIES
+	SEVERITY_ERROR   = "error"
+	SEVERITY_WARNING = "warning"
+	SEVERITY_INFO    = "info"
+
+	// AUDIT CHECKS
+	CHECK_LAPS_ORDER     = "laps-order"
+	CHECK_LAPS_COUNT     = "laps-count"
+	CHECK_LANE           = "lane"
+	CHECK_SERIES_GAP     = "series-gap"
+	CHECK_NULL_DISTANCE  = "null-distance"
+	CHECK_DUPLICATE_CLUB = "duplicate-club"
+	CHECK_CLUB_NAME      = "club-name"
+	CHECK_EMPTY_RACE     = "empty-race"
+)
+
+// Finding is a structural problem found in the data. ParticipantID and ClubID are zero when the finding is not about
+// a single participant or club, RaceID is zero for findings spanning several races.
+type Finding struct {
+	Check         string `json:"check"`
+	Severity      string `json:"severity"`
+	RaceID        int64  `json:"race_id"`
+	ParticipantID int64  `json:"participant_id"`
+	ClubID        int64  `json:"club_id"`
+	Message       string `json:"message"`
+}
+
+// SeverityLevel orders the severities, higher is more severe. Unknown severities are 0.
+func SeverityLevel(severity string) int {
+	switch severity {
+	case SEVERITY_ERROR:
+		return 3
+	case SEVERITY_WARNING:
+		return 2
+	case SEVERITY_INFO:
+		return 1
+	}
+	return 0
+}
+
+// NewAudit runs all the checks over the participants, which must be ordered by race, and the races without
+// participants. Findings are ordered by race, participant and check.
+func NewAudit(participants []db.AuditParticipantRow, emptyRaceIDs []int64) []Finding {
+	findings := make([]Finding, 0)
+	for _, raceID := range emptyRaceIDs {
+		findings = append(findings, Finding{
+			Check:    CHECK_EMPTY_RACE,
+			Severity: SEVERITY_WARNING,
+			RaceID:   raceID,
+			Message:  "race has no participants",
+		})
+	}
+
+	for start := 0; start < len(participants); {
+		end := start
+		for end < len(participants) && participants[end].RaceID == participants[start].RaceID {
+			end++
+		}
+
+		race := participants[start:end]
+		for idx := range race {
+			findings = append(findings, auditParticipant(&race[idx])...)
+		}
+		findings = append(findings, auditSeries(race)...)
+		findings = append(findings, auditDuplicateClubs(race)...)
+		start = end
+	}
+
+	findings = append(findings, auditClubNames(participants)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].RaceID != findings[j].RaceID {
+			return findings[i].RaceID < findings[j].RaceID
+		}
+		if findings[i].ParticipantID != findings[j].ParticipantID {
+			return findings[i].ParticipantID < findings[j].ParticipantID
+		}
+		return findings[i].Check < findings[j].Check
+	})
+	return findings
+}
+
+// auditParticipant checks the laps, lane and distance of a participant against its race. Lap counts are not checked
+// for participants that retired, were absent or have no laps at all.
+func auditParticipant(p *db.AuditParticipantRow) []Finding {
+	findings := make([]Finding, 0)
+	finding := func(check, severity, format string, args ...any) {
+		findings = append(findings, Finding{
+			Check:         check,
+			Severity:      severity,
+			RaceID:        p.RaceID,
+			ParticipantID: p.ID,
+			ClubID:        p.ClubID,
+			Message:       fmt.Sprintf(format, args...),
+		})
+	}
+
+	laps, err := ParseLaps(p.Laps)
+	if err != nil {
+		finding(CHECK_LAPS_ORDER, SEVERITY_ERROR, "%v", err)
+	} else if err := laps.Validate(0); err != nil {
+		finding(CHECK_LAPS_ORDER, SEVERITY_ERROR, "%v: %s", err, laps)
+	}
+
+	if p.RaceLaps != nil && len(p.Laps) > 0 && len(p.Laps) != int(*p.RaceLaps) && !p.IsRetired && !p.IsAbsent {
+		finding(CHECK_LAPS_COUNT, SEVERITY_WARNING, "%d laps in a race of %d", len(p.Laps), *p.RaceLaps)
+	}
+
+	if p.Lane != nil && (*p.Lane < 1 || (p.RaceLanes != nil && *p.Lane > *p.RaceLanes)) {
+		lanes := "unknown"
+		if p.RaceLanes != nil {
+			lanes = fmt.Sprintf("%d", *p.RaceLanes)
+		}
+		finding(CHECK_LANE, SEVERITY_ERROR, "lane %d in a race of %s lanes", *p.Lane, lanes)
+	}
+
+	if p.Distance == nil && !p.IsAbsent {
+		finding(CHECK_NULL_DISTANCE, SEVERITY_WARNING, "participant has no distance")
+	}
+
+	return findings
+}
+
+// auditSeries checks the series of the race go from 1 to the last one without gaps.
+func auditSeries(race []db.AuditParticipantRow) []Finding {
+	series := make(map[int16]bool)
+	var last int16
+	for _, p := range race {
+		if p.Series != nil {
+			series[*p.Series] = true
+			last = max(last, *p.Series)
+		}
+	}
+
+	missing := make([]string, 0)
+	for s := int16(1); s <= last; s++ {
+		if !series[s] {
+			missing = append(missing, fmt.Sprintf("%d", s))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return []Finding{{
+		Check:    CHECK_SERIES_GAP,
+		Severity: SEVERITY_WARNING,
+		RaceID:   race[0].RaceID,
+		Message:  fmt.Sprintf("missing series %s of %d", strings.Join(missing, ", "), last),
+	}}
+}
+
+// auditDuplicateClubs finds clubs with more than one crew in the same gender and category of a race. Crews with the
+// same raw names are errors, crews with different ones are usually branch teams and only informative.
+func auditDuplicateClubs(race []db.AuditParticipantRow) []Finding {
+	type crewKey struct {
+		clubID           int64
+		gender, category string
+	}
+
+	crews := make(map[crewKey][]*db.AuditParticipantRow)
+	keys := make([]crewKey, 0)
+	for idx := range race {
+		p := &race[idx]
+		key := crewKey{clubID: p.ClubID, gender: p.Gender, category: p.Category}
+		if _, ok := crews[key]; !ok {
+			keys = append(keys, key)
+		}
+		crews[key] = append(crews[key], p)
+	}
+
+	findings := make([]Finding, 0)
+	for _, key := range keys {
+		for _, p := range crews[key][1:] {
+			first := crews[key][0]
+			severity := SEVERITY_INFO
+			if strings.Join(p.ClubNames, "|") == strings.Join(first.ClubNames, "|") {
+				severity = SEVERITY_ERROR
+			}
+			findings = append(findings, Finding{
+				Check:         CHECK_DUPLICATE_CLUB,
+				Severity:      severity,
+				RaceID:        p.RaceID,
+				ParticipantID: p.ID,
+				ClubID:        p.ClubID,
+				Message: fmt.Sprintf("club already in %s %s as participant=%d (%s vs %s)",
+					key.gender, key.category, first.ID, formatClubNames(first.ClubNames), formatClubNames(p.ClubNames)),
+			})
+		}
+	}
+	return findings
+}
+
+// auditClubNames finds raw club names mapped to more than one club across races.
+func auditClubNames(participants []db.AuditParticipantRow) []Finding {
+	clubs := make(map[string]map[int64]int) // raw name to the participants of each club using it
+	names := make([]string, 0)
+	for _, p := range participants {
+		for _, name := range p.ClubNames {
+			name = strings.ToUpper(strings.TrimSpace(name))
+			if _, ok := clubs[name]; !ok {
+				clubs[name] = make(map[int64]int)
+				names = append(names, name)
+			}
+			clubs[name][p.ClubID]++
+		}
+	}
+	sort.Strings(names)
+
+	findings := make([]Finding, 0)
+	for _, name := range names {
+		if len(clubs[name]) < 2 {
+			continue
+		}
+
+		clubIDs := make([]int64, 0, len(clubs[name]))
+		for clubID := range clubs[name] {
+			clubIDs = append(clubIDs, clubID)
+		}
+		sort.Slice(clubIDs, func(i, j int) bool { return clubIDs[i] < clubIDs[j] })
+
+		mappings := make([]string, len(clubIDs))
+		for idx, clubID := range clubIDs {
+			mappings[idx] = fmt.Sprintf("club=%d (%d participants)", clubID, clubs[name][clubID])
+		}
+		findings = append(findings, Finding{
+			Check:    CHECK_CLUB_NAME,
+			Severity: SEVERITY_WARNING,
+			Message:  fmt.Sprintf("%q is mapped to %s", name, strings.Join(mappings, ", ")),
+		})
+	}
+	return findings
+}
+
+func formatClubNames(names []string) string {
+	if len(names) == 0 {
+		return "no names"
+	}
+	return strings.Join(names, "/")
+}
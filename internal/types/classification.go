@@ -0,0 +1,165 @@
+package types
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// CLASSIFICATION STATUSES
+	STATUS_CLASSIFIED   = "CLASSIFIED"
+	STATUS_GUEST        = "GUEST"
+	STATUS_RETIRED      = "RETIRED"
+	STATUS_DISQUALIFIED = "DISQUALIFIED"
+	STATUS_ABSENT       = "ABSENT"
+	STATUS_NO_TIME      = "NO_TIME"
+)
+
+// order of the statuses in a classification, classified crews first
+var statusOrder = map[string]int{
+	STATUS_CLASSIFIED:   0,
+	STATUS_GUEST:        1,
+	STATUS_RETIRED:      2,
+	STATUS_DISQUALIFIED: 3,
+	STATUS_ABSENT:       4,
+	STATUS_NO_TIME:      5,
+}
+
+// ClassificationEntry is the result of a participant. Only classified crews have a position, gaps and points.
+type ClassificationEntry struct {
+	Participant *Participant `json:"participant"`
+	Status      string       `json:"status"`
+	Position    int          `json:"position,omitempty"`
+
+	Time          time.Duration `json:"time"`           // final time including the time penalties
+	Gap           time.Duration `json:"gap"`            // to the winner
+	GapPercentage float64       `json:"gap_percentage"` // gap relative to the winner time
+	Interval      time.Duration `json:"interval"`       // to the crew ahead
+
+	Points float64 `json:"points"`
+}
+
+// Classification ranks the participants of a race sharing gender and category. All the series are ranked together by
+// time, the same way time trials and league races are classified.
+type Classification struct {
+	Gender   string                `json:"gender"`
+	Category string                `json:"category"`
+	Entries  []ClassificationEntry `json:"entries"`
+}
+
+// PointsTable assigns points to the classified positions of a race.
+type PointsTable struct {
+	Points []float64 `json:"points"` // points of each position, the first one goes to the winner
+	Rest   float64   `json:"rest"`   // points of the positions not in the table
+
+	Discards int `json:"discards"` // worst results of each club not counted in the league standings
+}
+
+// PointsFor returns the points of a one-based position.
+func (t *PointsTable) PointsFor(position int) float64 {
+	if position <= 0 {
+		return 0
+	}
+	if position <= len(t.Points) {
+		return t.Points[position-1]
+	}
+	return t.Rest
+}
+
+// NewClassifications classifies the participants per gender and category. Tied crews share the position and the
+// points, the next crew skips the tied positions. A nil points table gives no points.
+func NewClassifications(participants []Participant, points *PointsTable) []Classification {
+	groups := make(map[[2]string][]ClassificationEntry)
+	keys := make([][2]string, 0)
+	for idx := range participants {
+		participant := &participants[idx]
+		key := [2]string{participant.Gender, participant.Category}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], ClassificationEntry{
+			Participant: participant,
+			Status:      classificationStatus(participant),
+			Time:        participant.Laps.Time() + participant.PenaltyTime(),
+		})
+	}
+
+	classifications := make([]Classification, len(keys))
+	for idx, key := range keys {
+		classifications[idx] = Classification{Gender: key[0], Category: key[1], Entries: classify(groups[key], points)}
+	}
+	return classifications
+}
+
+func classify(entries []ClassificationEntry, points *PointsTable) []ClassificationEntry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Status != entries[j].Status {
+			return statusOrder[entries[i].Status] < statusOrder[entries[j].Status]
+		}
+		return entries[i].Time < entries[j].Time
+	})
+
+	var winner, previous time.Duration
+	for idx := range entries {
+		entry := &entries[idx]
+		if entry.Status != STATUS_CLASSIFIED {
+			continue
+		}
+
+		if idx == 0 {
+			winner, previous = entry.Time, entry.Time
+			entry.Position = 1
+		} else {
+			entry.Position = idx + 1
+			if entry.Time == previous {
+				entry.Position = entries[idx-1].Position
+			}
+		}
+
+		entry.Gap = entry.Time - winner
+		entry.GapPercentage = entry.Gap.Seconds() / winner.Seconds() * 100
+		entry.Interval = entry.Time - previous
+		previous = entry.Time
+	}
+
+	if points != nil {
+		assignPoints(entries, points)
+	}
+	return entries
+}
+
+// assignPoints gives each classified crew the points of its position, tied crews share the points of the positions
+// they cover.
+func assignPoints(entries []ClassificationEntry, points *PointsTable) {
+	for start := 0; start < len(entries) && entries[start].Status == STATUS_CLASSIFIED; {
+		end := start + 1
+		for end < len(entries) && entries[end].Status == STATUS_CLASSIFIED && entries[end].Position == entries[start].Position {
+			end++
+		}
+
+		var total float64
+		for position := start + 1; position <= end; position++ {
+			total += points.PointsFor(position)
+		}
+		for idx := start; idx < end; idx++ {
+			entries[idx].Points = total / float64(end-start)
+		}
+		start = end
+	}
+}
+
+func classificationStatus(participant *Participant) string {
+	switch {
+	case participant.IsAbsent:
+		return STATUS_ABSENT
+	case participant.IsDisqualified:
+		return STATUS_DISQUALIFIED
+	case participant.IsRetired:
+		return STATUS_RETIRED
+	case len(participant.Laps) == 0:
+		return STATUS_NO_TIME
+	case participant.IsGuest:
+		return STATUS_GUEST
+	}
+	return STATUS_CLASSIFIED
+}
@@ -0,0 +1,157 @@
+package types
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPointsFor(t *testing.T) {
+	table := &PointsTable{Points: []float64{10, 8, 6}, Rest: 1}
+	tests := []struct {
+		name     string
+		table    *PointsTable
+		position int
+		want     float64
+	}{
+		{"negative", table, -1, 0},
+		{"zero", table, 0, 0},
+		{"winner", table, 1, 10},
+		{"last in the table", table, 3, 6},
+		{"first after the table", table, 4, 1},
+		{"far after the table", table, 100, 1},
+		{"empty table", &PointsTable{}, 1, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.table.PointsFor(test.position); got != test.want {
+				t.Errorf("PointsFor(%d)=%v, want %v", test.position, got, test.want)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	type result struct {
+		status   string
+		position int
+		points   float64
+	}
+	entry := func(status string, seconds int) ClassificationEntry {
+		return ClassificationEntry{Participant: &Participant{}, Status: status, Time: time.Duration(seconds) * time.Second}
+	}
+	table := &PointsTable{Points: []float64{10, 8, 6, 4}, Rest: 1}
+
+	tests := []struct {
+		name    string
+		entries []ClassificationEntry
+		points  *PointsTable
+		want    []result
+	}{
+		{
+			name:    "no ties",
+			entries: []ClassificationEntry{entry(STATUS_CLASSIFIED, 62), entry(STATUS_CLASSIFIED, 60), entry(STATUS_CLASSIFIED, 61)},
+			points:  table,
+			want:    []result{{STATUS_CLASSIFIED, 1, 10}, {STATUS_CLASSIFIED, 2, 8}, {STATUS_CLASSIFIED, 3, 6}},
+		},
+		{
+			name:    "two-way tie",
+			entries: []ClassificationEntry{entry(STATUS_CLASSIFIED, 60), entry(STATUS_CLASSIFIED, 60), entry(STATUS_CLASSIFIED, 62)},
+			points:  table,
+			want:    []result{{STATUS_CLASSIFIED, 1, 9}, {STATUS_CLASSIFIED, 1, 9}, {STATUS_CLASSIFIED, 3, 6}},
+		},
+		{
+			name: "three-way tie",
+			entries: []ClassificationEntry{
+				entry(STATUS_CLASSIFIED, 60),
+				entry(STATUS_CLASSIFIED, 61),
+				entry(STATUS_CLASSIFIED, 61),
+				entry(STATUS_CLASSIFIED, 61),
+				entry(STATUS_CLASSIFIED, 65),
+			},
+			points: table,
+			want: []result{
+				{STATUS_CLASSIFIED, 1, 10},
+				{STATUS_CLASSIFIED, 2, 6},
+				{STATUS_CLASSIFIED, 2, 6},
+				{STATUS_CLASSIFIED, 2, 6},
+				{STATUS_CLASSIFIED, 5, 1},
+			},
+		},
+		{
+			name: "tie at the end of the table",
+			entries: []ClassificationEntry{
+				entry(STATUS_CLASSIFIED, 60),
+				entry(STATUS_CLASSIFIED, 61),
+				entry(STATUS_CLASSIFIED, 62),
+				entry(STATUS_CLASSIFIED, 63),
+				entry(STATUS_CLASSIFIED, 63),
+			},
+			points: table,
+			want: []result{
+				{STATUS_CLASSIFIED, 1, 10},
+				{STATUS_CLASSIFIED, 2, 8},
+				{STATUS_CLASSIFIED, 3, 6},
+				{STATUS_CLASSIFIED, 4, 2.5},
+				{STATUS_CLASSIFIED, 4, 2.5},
+			},
+		},
+		{
+			name: "not classified",
+			entries: []ClassificationEntry{
+				entry(STATUS_GUEST, 55),
+				entry(STATUS_CLASSIFIED, 62),
+				entry(STATUS_DISQUALIFIED, 50),
+				entry(STATUS_RETIRED, 0),
+				entry(STATUS_CLASSIFIED, 60),
+			},
+			points: table,
+			want: []result{
+				{STATUS_CLASSIFIED, 1, 10},
+				{STATUS_CLASSIFIED, 2, 8},
+				{STATUS_GUEST, 0, 0},
+				{STATUS_RETIRED, 0, 0},
+				{STATUS_DISQUALIFIED, 0, 0},
+			},
+		},
+		{
+			name:    "without points table",
+			entries: []ClassificationEntry{entry(STATUS_CLASSIFIED, 60), entry(STATUS_CLASSIFIED, 60), entry(STATUS_GUEST, 58)},
+			want:    []result{{STATUS_CLASSIFIED, 1, 0}, {STATUS_CLASSIFIED, 1, 0}, {STATUS_GUEST, 0, 0}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries := classify(test.entries, test.points)
+
+			got := make([]result, len(entries))
+			for idx, entry := range entries {
+				got[idx] = result{entry.Status, entry.Position, entry.Points}
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("classification=%v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestClassifyGaps(t *testing.T) {
+	entries := classify([]ClassificationEntry{
+		{Status: STATUS_CLASSIFIED, Time: 60 * time.Second},
+		{Status: STATUS_CLASSIFIED, Time: 63 * time.Second},
+		{Status: STATUS_CLASSIFIED, Time: 63 * time.Second},
+		{Status: STATUS_CLASSIFIED, Time: 66 * time.Second},
+	}, nil)
+
+	wantGaps := []time.Duration{0, 3 * time.Second, 3 * time.Second, 6 * time.Second}
+	wantIntervals := []time.Duration{0, 3 * time.Second, 0, 3 * time.Second}
+	for idx, entry := range entries {
+		if entry.Gap != wantGaps[idx] || entry.Interval != wantIntervals[idx] {
+			t.Errorf("entry=%d gap=%v interval=%v, want gap=%v interval=%v",
+				idx, entry.Gap, entry.Interval, wantGaps[idx], wantIntervals[idx])
+		}
+	}
+	if got := entries[3].GapPercentage; got != 10 {
+		t.Errorf("gap percentage=%v, want 10", got)
+	}
+}
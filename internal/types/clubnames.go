@@ -0,0 +1,52 @@
+package types
+
+const (
+	// MAPPING SUGGESTION REASONS
+	REASON_CONFLICT    = "conflict"    // the raw name is mostly used by another club
+	REASON_CLOSER_NAME = "closer-name" // the raw name is closer to the names of another club
+)
+
+// RawNameUsage is a raw name used by the participants of a club.
+type RawNameUsage struct {
+	RawName      string `json:"raw_name"`
+	Participants int    `json:"participants"`
+	Races        int    `json:"races"`
+}
+
+// ClubRawNames are all the raw names mapped to a club, ordered by usage.
+type ClubRawNames struct {
+	Club     *Entity        `json:"club"`
+	RawNames []RawNameUsage `json:"raw_names"`
+}
+
+// ClubUsage is a club using a raw name.
+type ClubUsage struct {
+	Club         *Entity `json:"club"`
+	Participants int     `json:"participants"`
+	Races        int     `json:"races"`
+}
+
+// RawNameConflict is a raw name mapped to more than one club, ordered by usage.
+type RawNameConflict struct {
+	RawName string      `json:"raw_name"`
+	Clubs   []ClubUsage `json:"clubs"`
+}
+
+// MappingSuggestion proposes moving the participants using a raw name from a club to another. Score is how well the
+// raw name matches the names of the suggested club and CurrentScore how well it matches the current one.
+type MappingSuggestion struct {
+	RawName      string  `json:"raw_name"`
+	From         *Entity `json:"from"`
+	To           *Entity `json:"to"`
+	Participants int     `json:"participants"`
+	Score        float64 `json:"score"`
+	CurrentScore float64 `json:"current_score"`
+	Reason       string  `json:"reason"`
+}
+
+// ClubNamesReview groups the raw names by club and lists the raw names that are likely mis-mapped.
+type ClubNamesReview struct {
+	Clubs       []ClubRawNames      `json:"clubs,omitempty"`
+	Conflicts   []RawNameConflict   `json:"conflicts"`
+	Suggestions []MappingSuggestion `json:"suggestions"`
+}
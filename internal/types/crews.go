@@ -0,0 +1,75 @@
+package types
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+)
+
+const (
+	// BRANCH MODES
+	BRANCH_ALL       = ""          // all the crews together
+	BRANCH_MAIN      = "main"      // only the main crews
+	BRANCH_ONLY      = "branch"    // only the branch teams
+	BRANCH_SEPARATED = "separated" // the main crews and each branch letter on their own
+
+	MAIN_CREW = "A" // branch letter of the main crews
+)
+
+// sponsorStart matches where the sponsor of a raw club name begins, after a " - " or in parentheses.
+var sponsorStart = regexp.MustCompile(` (- |\()`)
+
+// Crew is the identity of a participant parsed from the raw club name it raced with.
+type Crew struct {
+	Club    string `json:"club"`              // club name without the branch letter and the sponsor
+	Branch  string `json:"branch,omitempty"`  // branch letter, empty for the main crews
+	Sponsor string `json:"sponsor,omitempty"` // sponsor after a " - " or in parentheses
+}
+
+// ParseCrew splits a raw club name into its crew identity, "PUEBLA B (ESTRELLA GALICIA)" is the B crew of PUEBLA
+// sponsored by ESTRELLA GALICIA. Only the letters in [db.BRANCH_LETTERS] make a branch team, an "A" is dropped from
+// the club name as it is the main crew.
+func ParseCrew(rawName string) Crew {
+	crew := Crew{Club: strings.TrimSpace(rawName)}
+	if idx := sponsorStart.FindStringIndex(crew.Club); idx != nil {
+		crew.Club, crew.Sponsor = crew.Club[:idx[0]], strings.Trim(crew.Club[idx[0]:], " -()")
+	}
+
+	if fields := strings.Fields(crew.Club); len(fields) > 1 {
+		last := fields[len(fields)-1]
+		if last == MAIN_CREW || (len(last) == 1 && strings.Contains(db.BRANCH_LETTERS, last)) {
+			crew.Club = strings.Join(fields[:len(fields)-1], " ")
+			if last != MAIN_CREW {
+				crew.Branch = last
+			}
+		}
+	}
+	return crew
+}
+
+// NewCrew is the crew of a participant from all its raw club names, the first branch team found or the first name.
+func NewCrew(rawNames []string) Crew {
+	if len(rawNames) == 0 {
+		return Crew{}
+	}
+	for _, name := range rawNames {
+		if crew := ParseCrew(name); crew.IsBranch() {
+			return crew
+		}
+	}
+	return ParseCrew(rawNames[0])
+}
+
+// IsBranch reports if the crew is a branch team of its club.
+func (c Crew) IsBranch() bool {
+	return c.Branch != ""
+}
+
+// Letter is the branch letter of the crew, MAIN_CREW for the main crews.
+func (c Crew) Letter() string {
+	if c.IsBranch() {
+		return c.Branch
+	}
+	return MAIN_CREW
+}
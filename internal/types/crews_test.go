@@ -0,0 +1,66 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+)
+
+func TestParseCrew(t *testing.T) {
+	tests := []struct {
+		rawName string
+		want    Crew
+	}{
+		{"PUEBLA B (ESTRELLA GALICIA)", Crew{Club: "PUEBLA", Branch: "B", Sponsor: "ESTRELLA GALICIA"}},
+		{"PUEBLA C - ESTRELLA", Crew{Club: "PUEBLA", Branch: "C", Sponsor: "ESTRELLA"}},
+		{"PUEBLA - ESTRELLA B", Crew{Club: "PUEBLA", Sponsor: "ESTRELLA B"}},
+		{"PUEBLA A", Crew{Club: "PUEBLA"}},
+		{"PUEBLA", Crew{Club: "PUEBLA"}},
+		{"  CABO DA CRUZ E  ", Crew{Club: "CABO DA CRUZ", Branch: "E"}},
+		{"X F", Crew{Club: "X F"}},
+		{"PUEBLA BC", Crew{Club: "PUEBLA BC"}},
+		{"B", Crew{Club: "B"}},
+		{"", Crew{}},
+	}
+	for _, test := range tests {
+		t.Run(test.rawName, func(t *testing.T) {
+			if got := ParseCrew(test.rawName); got != test.want {
+				t.Errorf("ParseCrew(%q)=%+v, want %+v", test.rawName, got, test.want)
+			}
+		})
+	}
+}
+
+// TestParseCrewBranchLetters keeps ParseCrew in step with the branch letters filtered in the database.
+func TestParseCrewBranchLetters(t *testing.T) {
+	for _, letter := range db.BRANCH_LETTERS {
+		rawName := "PUEBLA " + string(letter)
+		if got := ParseCrew(rawName); got.Branch != string(letter) || got.Letter() != string(letter) {
+			t.Errorf("ParseCrew(%q)=%+v, want branch %c", rawName, got, letter)
+		}
+	}
+}
+
+func TestNewCrew(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawNames []string
+		want     Crew
+	}{
+		{"no names", nil, Crew{}},
+		{"main crew", []string{"PUEBLA A", "PUEBLA (ESTRELLA GALICIA)"}, Crew{Club: "PUEBLA"}},
+		{"first branch", []string{"PUEBLA", "PUEBLA B (ESTRELLA GALICIA)", "PUEBLA C"}, Crew{Club: "PUEBLA", Branch: "B", Sponsor: "ESTRELLA GALICIA"}},
+		{"branch in the sponsor", []string{"PUEBLA - ESTRELLA B", "X F"}, Crew{Club: "PUEBLA", Sponsor: "ESTRELLA B"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NewCrew(test.rawNames)
+			if got != test.want {
+				t.Errorf("NewCrew(%q)=%+v, want %+v", test.rawNames, got, test.want)
+			}
+			if got.IsBranch() != (test.want.Branch != "") {
+				t.Errorf("NewCrew(%q).IsBranch()=%t", test.rawNames, got.IsBranch())
+			}
+		})
+	}
+}
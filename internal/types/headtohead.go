@@ -0,0 +1,117 @@
+package types
+
+import (
+	"time"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+)
+
+// Meeting is a race where both clubs took part. Gap is the club time minus the rival time, negative when the club
+// was faster.
+type Meeting struct {
+	RaceID    int64         `json:"race_id"`
+	RaceName  string        `json:"race_name,omitempty"` // only loaded for the latest meetings
+	Date      time.Time     `json:"date"`
+	ClubTime  time.Duration `json:"club_time"`
+	RivalTime time.Duration `json:"rival_time"`
+	Gap       time.Duration `json:"gap"`
+}
+
+func NewMeetingFromDB(from *db.HeadToHeadRow) *Meeting {
+	clubTime := time.Duration(from.ClubTime * float64(time.Second))
+	rivalTime := time.Duration(from.RivalTime * float64(time.Second))
+	return &Meeting{
+		RaceID:    from.RaceID,
+		Date:      from.Date.Time,
+		ClubTime:  clubTime,
+		RivalTime: rivalTime,
+		Gap:       clubTime - rivalTime,
+	}
+}
+
+// YearRecord is the head to head record of a season.
+type YearRecord struct {
+	Year       int           `json:"year"`
+	Meetings   int           `json:"meetings"`
+	Wins       int           `json:"wins"`
+	AverageGap time.Duration `json:"average_gap"`
+}
+
+// HeadToHead is the record of a club against a rival. Trend is the change of the yearly average gap per year, negative
+// when the club is getting faster than the rival.
+type HeadToHead struct {
+	Club  *Entity `json:"club"`
+	Rival *Entity `json:"rival"`
+
+	Meetings []Meeting `json:"meetings"` // in chronological order
+	Wins     int       `json:"wins"`
+	Losses   int       `json:"losses"`
+	Draws    int       `json:"draws"`
+
+	AverageGap time.Duration `json:"average_gap"`
+	Years      []YearRecord  `json:"years"`
+	Trend      time.Duration `json:"trend"`
+}
+
+// NewHeadToHead summarises the meetings of two clubs, meetings must be in chronological order.
+func NewHeadToHead(club, rival *Entity, meetings []Meeting) *HeadToHead {
+	h := &HeadToHead{Club: club, Rival: rival, Meetings: meetings, Years: make([]YearRecord, 0)}
+	if len(meetings) == 0 {
+		return h
+	}
+
+	var total time.Duration
+	for _, meeting := range meetings {
+		switch {
+		case meeting.Gap < 0:
+			h.Wins++
+		case meeting.Gap > 0:
+			h.Losses++
+		default:
+			h.Draws++
+		}
+		total += meeting.Gap
+
+		year := meeting.Date.Year()
+		if len(h.Years) == 0 || h.Years[len(h.Years)-1].Year != year {
+			h.Years = append(h.Years, YearRecord{Year: year})
+		}
+		record := &h.Years[len(h.Years)-1]
+		record.Meetings++
+		record.AverageGap += meeting.Gap // summed until all the meetings are counted
+		if meeting.Gap < 0 {
+			record.Wins++
+		}
+	}
+	h.AverageGap = total / time.Duration(len(meetings))
+
+	for idx := range h.Years {
+		h.Years[idx].AverageGap /= time.Duration(h.Years[idx].Meetings)
+	}
+	h.Trend = gapTrend(h.Years)
+
+	return h
+}
+
+// gapTrend is the least squares slope of the yearly average gaps.
+func gapTrend(years []YearRecord) time.Duration {
+	if len(years) < 2 {
+		return 0
+	}
+
+	var meanX, meanY float64
+	for _, record := range years {
+		meanX += float64(record.Year)
+		meanY += float64(record.AverageGap)
+	}
+	meanX /= float64(len(years))
+	meanY /= float64(len(years))
+
+	var covariance, variance float64
+	for _, record := range years {
+		dx := float64(record.Year) - meanX
+		covariance += dx * (float64(record.AverageGap) - meanY)
+		variance += dx * dx
+	}
+	return time.Duration(covariance / variance)
+}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	utils "github.com/iagocanalejas/rstats/internal/utils/strings"
+)
+
+var ErrInvalidLaps = errors.New("invalid laps")
+
+// Laps are the cumulative times of a participant at the end of each lap, the last one being its final time.
+type Laps []time.Duration
+
+// ParseLaps parses the PostgreSQL intervals stored in the participant laps.
+func ParseLaps(values []string) (Laps, error) {
+	laps := make(Laps, len(values))
+	for idx, value := range values {
+		lap, err := utils.ParseInterval(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: lap %d: %w", ErrInvalidLaps, idx+1, err)
+		}
+		laps[idx] = lap
+	}
+	return laps, nil
+}
+
+// Time is the final time, zero when there are no laps.
+func (l Laps) Time() time.Duration {
+	if len(l) == 0 {
+		return 0
+	}
+	return l[len(l)-1]
+}
+
+// Splits returns the time of each lap on its own.
+func (l Laps) Splits() []time.Duration {
+	splits := make([]time.Duration, len(l))
+	var previous time.Duration
+	for idx, lap := range l {
+		splits[idx] = lap - previous
+		previous = lap
+	}
+	return splits
+}
+
+// Validate checks the laps are positive and strictly increasing. When expected is greater than zero the number of
+// laps must match it too.
+func (l Laps) Validate(expected int) error {
+	if expected > 0 && len(l) != expected {
+		return fmt.Errorf("%w: expected %d laps, got %d", ErrInvalidLaps, expected, len(l))
+	}
+
+	var previous time.Duration
+	for idx, lap := range l {
+		if lap <= previous {
+			return fmt.Errorf("%w: lap %d (%s) is not after the previous one (%s)", ErrInvalidLaps, idx+1, FormatLapTime(lap), FormatLapTime(previous))
+		}
+		previous = lap
+	}
+	return nil
+}
+
+func (l Laps) String() string {
+	formatted := make([]string, len(l))
+	for idx, lap := range l {
+		formatted[idx] = FormatLapTime(lap)
+	}
+	return strings.Join(formatted, " ")
+}
+
+// FormatLapTime formats a time as mm:ss.cc, minutes are not wrapped into hours.
+func FormatLapTime(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+
+	centiseconds := d.Round(10*time.Millisecond).Milliseconds() / 10
+	return fmt.Sprintf("%s%02d:%02d.%02d", sign, centiseconds/6000, (centiseconds/100)%60, centiseconds%100)
+}
@@ -0,0 +1,126 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Split is a single lap of a participant, its speed is computed from the distance of one lap.
+type Split struct {
+	Lap   int           `json:"lap"` // one-based
+	Time  time.Duration `json:"time"`
+	Speed float64       `json:"speed"` // km/h
+}
+
+// Pacing describes how a participant distributed its effort along the race.
+type Pacing struct {
+	Splits []Split `json:"splits"`
+
+	Speed float64 `json:"speed"` // average speed of the whole race in km/h
+
+	// HalvesRatio is the time of the second half of the race divided by the time of the first one. Values over 1 mean
+	// the crew slowed down, the middle lap of odd races is split between both halves.
+	HalvesRatio float64 `json:"halves_ratio"`
+
+	// Fade is how much slower (in %) the last lap was compared with the average speed of the previous ones, negative
+	// values mean the crew finished faster.
+	Fade float64 `json:"fade"`
+}
+
+// NewPacing computes the splits and pacing metrics of the given laps. Each lap is assumed to be distance / raceLaps
+// meters long, so the laps must be valid and match the race laps.
+func NewPacing(laps Laps, distance int, raceLaps int) (*Pacing, error) {
+	if distance <= 0 {
+		return nil, fmt.Errorf("%w: invalid distance=%d", ErrInvalidLaps, distance)
+	}
+	if raceLaps < 2 {
+		return nil, fmt.Errorf("%w: pacing needs at least 2 laps, got %d", ErrInvalidLaps, raceLaps)
+	}
+	if err := laps.Validate(raceLaps); err != nil {
+		return nil, err
+	}
+
+	lapDistance := float64(distance) / float64(raceLaps)
+	splitTimes := laps.Splits()
+	splits := make([]Split, len(splitTimes))
+	for idx, split := range splitTimes {
+		splits[idx] = Split{Lap: idx + 1, Time: split, Speed: speed(lapDistance, split)}
+	}
+
+	return &Pacing{
+		Splits:      splits,
+		Speed:       speed(float64(distance), laps.Time()),
+		HalvesRatio: halvesRatio(splitTimes),
+		Fade:        fade(splits),
+	}, nil
+}
+
+func halvesRatio(splits []time.Duration) float64 {
+	var first, second float64
+	half := len(splits) / 2
+	for idx, split := range splits {
+		switch {
+		case idx < half:
+			first += split.Seconds()
+		case len(splits)%2 == 1 && idx == half:
+			first += split.Seconds() / 2
+			second += split.Seconds() / 2
+		default:
+			second += split.Seconds()
+		}
+	}
+	return second / first
+}
+
+func fade(splits []Split) float64 {
+	var previous float64
+	for _, split := range splits[:len(splits)-1] {
+		previous += split.Speed
+	}
+	previous /= float64(len(splits) - 1)
+
+	return (previous - splits[len(splits)-1].Speed) / previous * 100
+}
+
+// PacingProfile summarises the pacing of a club across several races with the same number of laps.
+type PacingProfile struct {
+	Laps  int `json:"laps"`
+	Races int `json:"races"`
+
+	// RelativeSpeeds is the average speed of each lap relative to the race speed, 1.02 means a lap 2% faster than
+	// the average of the race.
+	RelativeSpeeds []float64 `json:"relative_speeds"`
+
+	HalvesRatio float64 `json:"halves_ratio"`
+	Fade        float64 `json:"fade"`
+}
+
+// NewPacingProfile averages the given pacings, all of them must have the same number of splits.
+func NewPacingProfile(pacings []*Pacing) *PacingProfile {
+	if len(pacings) == 0 {
+		return nil
+	}
+
+	laps := len(pacings[0].Splits)
+	profile := &PacingProfile{Laps: laps, Races: len(pacings), RelativeSpeeds: make([]float64, laps)}
+	for _, pacing := range pacings {
+		for idx, split := range pacing.Splits {
+			profile.RelativeSpeeds[idx] += split.Speed / pacing.Speed
+		}
+		profile.HalvesRatio += pacing.HalvesRatio
+		profile.Fade += pacing.Fade
+	}
+
+	for idx := range profile.RelativeSpeeds {
+		profile.RelativeSpeeds[idx] /= float64(len(pacings))
+	}
+	profile.HalvesRatio /= float64(len(pacings))
+	profile.Fade /= float64(len(pacings))
+
+	return profile
+}
+
+// speed in km/h
+func speed(meters float64, d time.Duration) float64 {
+	return meters / d.Seconds() * 3.6
+}
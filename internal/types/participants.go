@@ -1,7 +1,11 @@
 package types
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/iagocanalejas/rstats/internal/db"
+	"github.com/lib/pq"
 )
 
 type Participant struct {
@@ -13,19 +17,30 @@ type Participant struct {
 	Distance int    `json:"distance"`
 
 	Club *Entity `json:"club"`
+	Crew Crew    `json:"crew"` // parsed from the raw club names
 
-	IsDisqualified bool `json:"disqualified"`
+	IsDisqualified bool      `json:"disqualified"`
+	IsRetired      bool      `json:"retired"`
+	IsGuest        bool      `json:"guest"`
+	IsAbsent       bool      `json:"absent"`
+	Penalties      []Penalty `json:"penalties,omitempty"`
 
-	Laps   *[]string `json:"laps"`
-	Lane   *int16    `json:"lane"`
-	Series *int16    `json:"series"`
+	Laps   Laps   `json:"laps"`
+	Lane   *int16 `json:"lane"`
+	Series *int16 `json:"series"`
 
-	Speed *float64 `json:"speed"`
+	Speed    *float64   `json:"speed"`
+	RaceDate *time.Time `json:"race_date,omitempty"` // only loaded with the speed
 }
 
-func NewParticipantFromDB(from *db.ParticipantRow) *Participant {
+func NewParticipantFromDB(from *db.ParticipantRow) (*Participant, error) {
 	club := NewEntityFromDB(&db.EntityRow{ID: from.ClubId, Name: from.ClubName}, (*[]string)(from.ClubRawNames))
 
+	laps, err := parseParticipantLaps(from.ID, from.Laps)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Participant{
 		ID:     from.ID,
 		RaceID: from.RaceID,
@@ -35,20 +50,30 @@ func NewParticipantFromDB(from *db.ParticipantRow) *Participant {
 		Distance: from.Distance,
 
 		Club: club,
+		Crew: newParticipantCrew(from.ClubRawNames),
 
-		IsDisqualified: false,
+		IsDisqualified: from.IsDisqualified,
+		IsRetired:      from.IsRetired,
+		IsGuest:        from.IsGuest,
+		IsAbsent:       from.IsAbsent,
 
-		Laps:   (*[]string)(from.Laps),
+		Laps:   laps,
 		Lane:   from.Lane,
 		Series: from.Series,
 
-		Speed: nil,
-	}
+		Speed:    nil,
+		RaceDate: nil,
+	}, nil
 }
 
-func NewParticipantWithSpeedFromDB(from *db.ParticipantRowWithSpeed) *Participant {
+func NewParticipantWithSpeedFromDB(from *db.ParticipantRowWithSpeed) (*Participant, error) {
 	club := NewEntityFromDB(&db.EntityRow{ID: from.ClubId, Name: from.ClubName}, (*[]string)(from.ClubRawNames))
 
+	laps, err := parseParticipantLaps(from.ID, from.Laps)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Participant{
 		ID:     from.ID,
 		RaceID: from.RaceID,
@@ -58,13 +83,66 @@ func NewParticipantWithSpeedFromDB(from *db.ParticipantRowWithSpeed) *Participan
 		Distance: from.Distance,
 
 		Club: club,
+		Crew: newParticipantCrew(from.ClubRawNames),
 
-		IsDisqualified: false,
+		IsDisqualified: from.IsDisqualified,
 
-		Laps:   (*[]string)(from.Laps),
+		Laps:   laps,
 		Lane:   from.Lane,
 		Series: from.Series,
 
-		Speed: &from.Speed,
+		Speed:    &from.Speed,
+		RaceDate: &from.Date.Time,
+	}, nil
+}
+
+func newParticipantCrew(rawNames *pq.StringArray) Crew {
+	if rawNames == nil {
+		return Crew{}
+	}
+	return NewCrew(*rawNames)
+}
+
+func parseParticipantLaps(participantID int64, values *pq.StringArray) (Laps, error) {
+	if values == nil {
+		return nil, nil
+	}
+	laps, err := ParseLaps(*values)
+	if err != nil {
+		return nil, fmt.Errorf("participant=%d: %w", participantID, err)
+	}
+	return laps, nil
+}
+
+// IsPenalized reports if the participant has any penalty, disqualifying or not.
+func (p *Participant) IsPenalized() bool {
+	return len(p.Penalties) > 0
+}
+
+// PenaltyTime is the total time added to the participant by its penalties.
+func (p *Participant) PenaltyTime() time.Duration {
+	var total time.Duration
+	for _, penalty := range p.Penalties {
+		total += penalty.Time
+	}
+	return total
+}
+
+// DatedSpeed is the speed of a participant with the date of its race.
+type DatedSpeed struct {
+	ParticipantID int64     `json:"participant_id"`
+	RaceID        int64     `json:"race_id"`
+	ClubID        int64     `json:"club_id"`
+	Date          time.Time `json:"date"`
+	Speed         float64   `json:"speed"`
+}
+
+func NewDatedSpeedFromDB(from *db.DatedSpeedRow) *DatedSpeed {
+	return &DatedSpeed{
+		ParticipantID: from.ParticipantID,
+		RaceID:        from.RaceID,
+		ClubID:        from.ClubID,
+		Date:          from.Date.Time,
+		Speed:         from.Speed,
 	}
 }
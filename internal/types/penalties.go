@@ -0,0 +1,24 @@
+package types
+
+import (
+	"time"
+
+	"github.com/iagocanalejas/rstats/internal/db"
+)
+
+type Penalty struct {
+	ID int64 `json:"id"`
+
+	Time             time.Duration `json:"time"` // added to the participant time
+	Disqualification bool          `json:"disqualification"`
+	Reason           *string       `json:"reason,omitempty"`
+}
+
+func NewPenaltyFromDB(from *db.PenaltyRow) *Penalty {
+	return &Penalty{
+		ID:               from.ID,
+		Time:             time.Duration(from.Penalty) * time.Second,
+		Disqualification: from.Disqualification,
+		Reason:           from.Reason,
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/iagocanalejas/rstats/internal/db"
-	"github.com/iagocanalejas/rstats/internal/utils/assert"
 	"github.com/iagocanalejas/rstats/internal/utils/strings"
 )
 
@@ -43,25 +42,41 @@ type Race struct {
 	Metadata *RaceMetadata `json:"metadata"`
 
 	Participants []Participant `json:"participants"`
+
+	Match *RaceMatch `json:"match,omitempty"` // only set by ranked searches
+}
+
+// RaceMatch is the race name that best matched the keywords of a ranked search.
+type RaceMatch struct {
+	Field string  `json:"field"` // one of db.MATCH_*
+	Value string  `json:"value"`
+	Score float64 `json:"score"`
 }
 
 func NewRaceFromDB(from *db.RaceRow) *Race {
+	// names may be missing when the row was not joined with its trophy, flag or league, they are left empty then
 	var trophy *Trophy
 	if from.TrophyID != nil {
-		assert.NotNil(from.TrophyName, "trophy name is nil")
-		trophy = &Trophy{ID: *from.TrophyID, Name: *from.TrophyName, Edition: from.TrophyEdition}
+		trophy = &Trophy{ID: *from.TrophyID, Edition: from.TrophyEdition}
+		if from.TrophyName != nil {
+			trophy.Name = *from.TrophyName
+		}
 	}
 
 	var flag *Flag
 	if from.FlagID != nil {
-		assert.NotNil(from.FlagName, "flag name is nil")
-		flag = &Flag{ID: *from.FlagID, Name: *from.FlagName, Edition: from.FlagEdition}
+		flag = &Flag{ID: *from.FlagID, Edition: from.FlagEdition}
+		if from.FlagName != nil {
+			flag.Name = *from.FlagName
+		}
 	}
 
 	var league *League
 	if from.LeagueID != nil {
-		assert.NotNil(from.LeagueName, "league name is nil")
-		league = &League{ID: *from.LeagueID, Name: *from.LeagueName, Gender: from.LeagueGender, Category: from.LeagueCategory}
+		league = &League{ID: *from.LeagueID, Gender: from.LeagueGender, Category: from.LeagueCategory}
+		if from.LeagueName != nil {
+			league.Name = *from.LeagueName
+		}
 	}
 
 	var metadata *RaceMetadata
@@ -69,6 +84,15 @@ func NewRaceFromDB(from *db.RaceRow) *Race {
 		_ = json.Unmarshal([]byte(from.Metadata), &metadata)
 	}
 
+	// rows without the match of a ranked search have no match, a missing score counts as 0
+	var match *RaceMatch
+	if from.MatchField != nil && from.MatchValue != nil {
+		match = &RaceMatch{Field: *from.MatchField, Value: *from.MatchValue}
+		if from.MatchScore != nil {
+			match.Score = *from.MatchScore
+		}
+	}
+
 	return &Race{
 		ID:   from.ID,
 		Name: buildRaceName(from, false),
@@ -92,6 +116,8 @@ func NewRaceFromDB(from *db.RaceRow) *Race {
 		Sponsor: from.Sponsor,
 
 		Metadata: metadata,
+
+		Match: match,
 	}
 }
 
@@ -107,13 +133,13 @@ func buildRaceName(race *db.RaceRow, associated bool) string {
 	}
 
 	trophy := ""
-	if race.TrophyID != nil && *race.TrophyEdition > 0 {
+	if race.TrophyID != nil && race.TrophyName != nil && race.TrophyEdition != nil && *race.TrophyEdition > 0 {
 		trophy = fmt.Sprintf("%s - %s", utils.Int2Roman(*race.TrophyEdition), *race.TrophyName)
 		trophy = strings.Replace(trophy, "(CLASIFICATORIA)", "", -1)
 	}
 
 	flag := ""
-	if race.FlagID != nil && *race.FlagEdition > 0 {
+	if race.FlagID != nil && race.FlagName != nil && race.FlagEdition != nil && *race.FlagEdition > 0 {
 		flag = fmt.Sprintf("%s - %s", utils.Int2Roman(*race.FlagEdition), *race.FlagName)
 	}
 
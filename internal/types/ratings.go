@@ -0,0 +1,248 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// RATING SYSTEMS
+	RATING_ELO     = "elo"
+	RATING_GLICKO2 = "glicko2"
+
+	INITIAL_RATING     = 1500.0
+	INITIAL_RD         = 350.0
+	INITIAL_VOLATILITY = 0.06
+
+	ELO_K = 32.0 // maximum change of an Elo rating in a race
+
+	GLICKO_SCALE   = 173.7178
+	GLICKO_TAU     = 0.5                 // constrains the change of the volatility
+	GLICKO_EPSILON = 0.000001            // convergence of the volatility iteration
+	GLICKO_PERIOD  = 30 * 24 * time.Hour // inactivity time that increases the deviation by one volatility
+)
+
+var ErrInvalidRatingSystem = errors.New("invalid rating system")
+
+// RatingPoint is the rating of a club after a race.
+type RatingPoint struct {
+	RaceID int64     `json:"race_id"`
+	Date   time.Time `json:"date"`
+	Rating float64   `json:"rating"`
+	RD     float64   `json:"rd"`
+}
+
+// ClubRating is the current rating of a club. RD is the rating deviation, the uncertainty of the rating, and is only
+// computed by Glicko-2.
+type ClubRating struct {
+	Club       *Entity   `json:"club"`
+	Rating     float64   `json:"rating"`
+	RD         float64   `json:"rd"`
+	Volatility float64   `json:"volatility"`
+	Races      int       `json:"races"`
+	LastRace   time.Time `json:"last_race"`
+
+	History []RatingPoint `json:"history"`
+}
+
+// Ratings rates the clubs of a gender and category. Each race is a match between all the participating clubs where
+// every club beats the clubs with a lower speed, so clubs that never met get compared through their common rivals.
+type Ratings struct {
+	System   string `json:"system"`
+	Gender   string `json:"gender"`
+	Category string `json:"category"`
+
+	clubs map[int64]*ClubRating
+}
+
+func NewRatings(system, gender, category string) (*Ratings, error) {
+	if system != RATING_ELO && system != RATING_GLICKO2 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRatingSystem, system)
+	}
+	return &Ratings{System: system, Gender: gender, Category: category, clubs: make(map[int64]*ClubRating)}, nil
+}
+
+// AddRace updates the ratings with the result of a race, participants need a speed. Only the fastest crew of each
+// club is rated so branch teams don't rate against their own club.
+func (r *Ratings) AddRace(raceID int64, date time.Time, participants []*Participant) {
+	sorted := make([]*Participant, 0, len(participants))
+	for _, participant := range participants {
+		if participant.Speed != nil {
+			sorted = append(sorted, participant)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return *sorted[i].Speed > *sorted[j].Speed })
+
+	crews := make([]*Participant, 0, len(sorted))
+	seen := make(map[int64]bool)
+	for _, participant := range sorted {
+		if !seen[participant.Club.ID] {
+			seen[participant.Club.ID] = true
+			crews = append(crews, participant)
+		}
+	}
+	if len(crews) < 2 {
+		return
+	}
+
+	players := make([]*ClubRating, len(crews))
+	for idx, crew := range crews {
+		players[idx] = r.club(crew.Club)
+	}
+
+	// all the updates use the ratings before the race
+	var updated []ClubRating
+	switch r.System {
+	case RATING_ELO:
+		updated = eloRace(crews, players)
+	case RATING_GLICKO2:
+		updated = glicko2Race(crews, players, date)
+	}
+
+	for idx, player := range players {
+		player.Rating, player.RD, player.Volatility = updated[idx].Rating, updated[idx].RD, updated[idx].Volatility
+		player.Races++
+		player.LastRace = date
+		player.History = append(player.History, RatingPoint{RaceID: raceID, Date: date, Rating: player.Rating, RD: player.RD})
+	}
+}
+
+// Club returns the rating of a club, nil when it was never rated.
+func (r *Ratings) Club(clubID int64) *ClubRating {
+	return r.clubs[clubID]
+}
+
+// Leaderboard returns the clubs with at least the given races ordered by rating.
+func (r *Ratings) Leaderboard(minRaces int) []ClubRating {
+	leaderboard := make([]ClubRating, 0, len(r.clubs))
+	for _, club := range r.clubs {
+		if club.Races >= minRaces {
+			leaderboard = append(leaderboard, *club)
+		}
+	}
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Rating != leaderboard[j].Rating {
+			return leaderboard[i].Rating > leaderboard[j].Rating
+		}
+		return leaderboard[i].Club.ID < leaderboard[j].Club.ID
+	})
+	return leaderboard
+}
+
+func (r *Ratings) club(club *Entity) *ClubRating {
+	rating, ok := r.clubs[club.ID]
+	if !ok {
+		rating = &ClubRating{Club: club, Rating: INITIAL_RATING, Volatility: INITIAL_VOLATILITY}
+		if r.System == RATING_GLICKO2 {
+			rating.RD = INITIAL_RD
+		}
+		r.clubs[club.ID] = rating
+	}
+	return rating
+}
+
+// raceScore is 1 when a beats b, 0 when it loses and 0.5 on the same speed.
+func raceScore(a, b *Participant) float64 {
+	switch {
+	case *a.Speed > *b.Speed:
+		return 1
+	case *a.Speed < *b.Speed:
+		return 0
+	}
+	return 0.5
+}
+
+// eloRace applies the Elo expected score to each pair of clubs, the change is averaged over the rivals so the K
+// factor is the same for any number of participants.
+func eloRace(crews []*Participant, players []*ClubRating) []ClubRating {
+	updated := make([]ClubRating, len(players))
+	for i, player := range players {
+		var delta float64
+		for j, rival := range players {
+			if i == j {
+				continue
+			}
+			expected := 1 / (1 + math.Pow(10, (rival.Rating-player.Rating)/400))
+			delta += raceScore(crews[i], crews[j]) - expected
+		}
+		updated[i] = ClubRating{Rating: player.Rating + ELO_K*delta/float64(len(players)-1), Volatility: player.Volatility}
+	}
+	return updated
+}
+
+// glicko2Race rates the race as a Glicko-2 rating period where each club plays against all the others.
+//  1. Increases the deviation of each club for the periods it has been inactive.
+//  2. Computes the estimated variance and improvement against the rivals.
+//  3. Updates the volatility, the deviation and the rating.
+func glicko2Race(crews []*Participant, players []*ClubRating, date time.Time) []ClubRating {
+	mu, phi := make([]float64, len(players)), make([]float64, len(players))
+	for idx, player := range players {
+		mu[idx] = (player.Rating - INITIAL_RATING) / GLICKO_SCALE
+		phi[idx] = player.RD / GLICKO_SCALE
+		if !player.LastRace.IsZero() {
+			periods := float64(date.Sub(player.LastRace)) / float64(GLICKO_PERIOD)
+			phi[idx] = math.Min(math.Sqrt(phi[idx]*phi[idx]+periods*player.Volatility*player.Volatility), INITIAL_RD/GLICKO_SCALE)
+		}
+	}
+
+	updated := make([]ClubRating, len(players))
+	for i, player := range players {
+		var variance, improvement float64
+		for j := range players {
+			if i == j {
+				continue
+			}
+			g := 1 / math.Sqrt(1+3*phi[j]*phi[j]/(math.Pi*math.Pi))
+			expected := 1 / (1 + math.Exp(-g*(mu[i]-mu[j])))
+			variance += g * g * expected * (1 - expected)
+			improvement += g * (raceScore(crews[i], crews[j]) - expected)
+		}
+		v := 1 / variance
+		delta := v * improvement
+
+		sigma := glicko2Volatility(phi[i], player.Volatility, v, delta)
+		phiStar := math.Sqrt(phi[i]*phi[i] + sigma*sigma)
+		newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+		newMu := mu[i] + newPhi*newPhi*improvement
+
+		updated[i] = ClubRating{Rating: newMu*GLICKO_SCALE + INITIAL_RATING, RD: newPhi * GLICKO_SCALE, Volatility: sigma}
+	}
+	return updated
+}
+
+// glicko2Volatility solves the new volatility with the Illinois algorithm as described in the Glicko-2 paper.
+func glicko2Volatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		return ex*(delta*delta-phi*phi-v-ex)/(2*math.Pow(phi*phi+v+ex, 2)) - (x-a)/(GLICKO_TAU*GLICKO_TAU)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*GLICKO_TAU) < 0 {
+			k++
+		}
+		B = a - k*GLICKO_TAU
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > GLICKO_EPSILON {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
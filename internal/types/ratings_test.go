@@ -0,0 +1,123 @@
+package types
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func racing(club *Entity, branch string, speed float64) *Participant {
+	return &Participant{Club: club, Crew: Crew{Club: club.Name, Branch: branch}, Speed: &speed}
+}
+
+// TestGlicko2Paper reproduces the example of Glickman's "Example of the Glicko-2 system": a player rated 1500 with a
+// deviation of 200 beats a 1400 and loses against a 1550 and a 1700.
+func TestGlicko2Paper(t *testing.T) {
+	crews := []*Participant{
+		racing(&Entity{ID: 1}, "", 2),
+		racing(&Entity{ID: 2}, "", 1),
+		racing(&Entity{ID: 3}, "", 3),
+		racing(&Entity{ID: 4}, "", 4),
+	}
+	players := []*ClubRating{
+		{Rating: 1500, RD: 200, Volatility: INITIAL_VOLATILITY},
+		{Rating: 1400, RD: 30, Volatility: INITIAL_VOLATILITY},
+		{Rating: 1550, RD: 100, Volatility: INITIAL_VOLATILITY},
+		{Rating: 1700, RD: 300, Volatility: INITIAL_VOLATILITY},
+	}
+
+	// the rivals also race between them, but only the first player matches the paper
+	got := glicko2Race(crews, players, time.Now())[0]
+	tests := []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"rating", got.Rating, 1464.06, 0.01},
+		{"rd", got.RD, 151.52, 0.01},
+		{"volatility", got.Volatility, 0.05999, 0.00001},
+	}
+	for _, test := range tests {
+		if math.Abs(test.got-test.want) > test.tolerance {
+			t.Errorf("%s=%v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestEloAveragesOverRivals(t *testing.T) {
+	tests := []struct {
+		name   string
+		rivals int
+	}{
+		{"one rival", 1},
+		{"three rivals", 3},
+		{"eight rivals", 8},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ratings, err := NewRatings(RATING_ELO, GENDER_MALE, CATEGORY_ABSOLUT)
+			if err != nil {
+				t.Fatalf("NewRatings: %v", err)
+			}
+
+			participants := make([]*Participant, test.rivals+1)
+			for idx := range participants {
+				participants[idx] = racing(&Entity{ID: int64(idx + 1)}, "", float64(20-idx))
+			}
+			ratings.AddRace(1, time.Now(), participants)
+
+			// the winner beats every rival with the same rating, scoring K/2 however many they are
+			if got, want := ratings.Club(1).Rating, INITIAL_RATING+ELO_K/2; got != want {
+				t.Errorf("winner rating=%v, want %v", got, want)
+			}
+			if got, want := ratings.Club(int64(test.rivals+1)).Rating, INITIAL_RATING-ELO_K/2; got != want {
+				t.Errorf("last rating=%v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRatingsBranchCrews(t *testing.T) {
+	tests := []struct {
+		name         string
+		participants []*Participant
+		want         map[int64]float64 // club to rating, zero for clubs not rated
+	}{
+		{
+			name:         "only branch crews",
+			participants: []*Participant{racing(tiran, "", 20), racing(tiran, "B", 19)},
+			want:         map[int64]float64{tiran.ID: 0},
+		},
+		{
+			name:         "branch crew between",
+			participants: []*Participant{racing(tiran, "", 20), racing(tiran, "B", 19), racing(mecos, "", 18)},
+			want:         map[int64]float64{tiran.ID: INITIAL_RATING + ELO_K/2, mecos.ID: INITIAL_RATING - ELO_K/2},
+		},
+		{
+			name:         "branch crew behind a rival",
+			participants: []*Participant{racing(tiran, "", 20), racing(mecos, "", 19), racing(tiran, "B", 18)},
+			want:         map[int64]float64{tiran.ID: INITIAL_RATING + ELO_K/2, mecos.ID: INITIAL_RATING - ELO_K/2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ratings, err := NewRatings(RATING_ELO, GENDER_MALE, CATEGORY_ABSOLUT)
+			if err != nil {
+				t.Fatalf("NewRatings: %v", err)
+			}
+			ratings.AddRace(1, time.Now(), test.participants)
+
+			for clubID, want := range test.want {
+				rating := ratings.Club(clubID)
+				switch {
+				case want == 0 && rating != nil:
+					t.Errorf("club=%d rated %v against its own crews", clubID, rating.Rating)
+				case want != 0 && rating == nil:
+					t.Errorf("club=%d not rated", clubID)
+				case want != 0 && (rating.Rating != want || rating.Races != 1):
+					t.Errorf("club=%d rating=%v races=%d, want %v in 1 race", clubID, rating.Rating, rating.Races, want)
+				}
+			}
+		})
+	}
+}
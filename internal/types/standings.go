@@ -0,0 +1,185 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RoundResult is the result of a club in a round of the league, Position is zero when the club did not classify.
+type RoundResult struct {
+	RaceID    int64   `json:"race_id"`
+	Position  int     `json:"position,omitempty"`
+	Points    float64 `json:"points"`
+	Discarded bool    `json:"discarded"`
+}
+
+// StandingsEntry is the season of a crew, Results has one entry per round in the same order as [Standings.Rounds].
+type StandingsEntry struct {
+	Position int     `json:"position"`
+	Club     *Entity `json:"club"`
+	Branch   string  `json:"branch,omitempty"` // branch letter of the crew, empty for the main crews
+	Points   float64 `json:"points"`           // without the discarded results
+
+	Results []RoundResult `json:"results"`
+}
+
+// Name is the club name followed by the branch letter for branch crews.
+func (e *StandingsEntry) Name() string {
+	if e.Branch != "" {
+		return fmt.Sprintf("%s %s", e.Club.Name, e.Branch)
+	}
+	return e.Club.Name
+}
+
+// Standings is the points table of a league season. Progression holds the standings after each round, the last one
+// being the same as Entries.
+type Standings struct {
+	League   *League `json:"league"`
+	Year     int     `json:"year"`
+	Gender   string  `json:"gender"`
+	Category string  `json:"category"`
+
+	Rounds      []Race             `json:"rounds"`
+	Entries     []StandingsEntry   `json:"entries"`
+	Progression [][]StandingsEntry `json:"progression"`
+}
+
+// DescendingPointsTable gives as many points to the winner as crews classified, one less to the second and so on.
+func DescendingPointsTable(crews int) *PointsTable {
+	points := make([]float64, crews)
+	for idx := range points {
+		points[idx] = float64(crews - idx)
+	}
+	return &PointsTable{Points: points}
+}
+
+// NewStandings aggregates the classifications of each round. Each crew has its own entry, so the branch crews of a
+// club are ranked apart from its main crew. The worst results are discarded as configured in the points table and ties
+// are broken by the best positions: most wins, then most second places and so on.
+func NewStandings(rounds []Race, classifications []*Classification, discards int) []StandingsEntry {
+	type crewKey struct {
+		clubID int64
+		branch string
+	}
+
+	entries := make([]StandingsEntry, 0)
+	crews := make(map[crewKey]int) // crew to entry index
+
+	for round, classification := range classifications {
+		if classification == nil {
+			continue
+		}
+		for _, result := range classification.Entries {
+			if result.Status != STATUS_CLASSIFIED {
+				continue
+			}
+
+			club, branch := result.Participant.Club, result.Participant.Crew.Branch
+			key := crewKey{clubID: club.ID, branch: branch}
+			idx, ok := crews[key]
+			if !ok {
+				idx = len(entries)
+				crews[key] = idx
+				entries = append(entries, StandingsEntry{Club: club, Branch: branch, Results: make([]RoundResult, len(rounds))})
+			}
+			if entries[idx].Results[round].Position > 0 {
+				// entries are sorted, a crew raced twice in the round only counts with its best result
+				continue
+			}
+			entries[idx].Results[round] = RoundResult{RaceID: rounds[round].ID, Position: result.Position, Points: result.Points}
+		}
+	}
+
+	for idx := range entries {
+		entry := &entries[idx]
+		for round := range entry.Results {
+			entry.Results[round].RaceID = rounds[round].ID
+		}
+		discardWorst(entry.Results, discards)
+		for _, result := range entry.Results {
+			if !result.Discarded {
+				entry.Points += result.Points
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if order := compareStandings(&entries[i], &entries[j]); order != 0 {
+			return order < 0
+		}
+		return strings.Compare(entries[i].Name(), entries[j].Name()) < 0
+	})
+	for idx := range entries {
+		entries[idx].Position = idx + 1
+		if idx > 0 && compareStandings(&entries[idx-1], &entries[idx]) == 0 {
+			entries[idx].Position = entries[idx-1].Position
+		}
+	}
+
+	return entries
+}
+
+// discardWorst marks the results with fewer points as discarded, the latest ones are discarded first on ties.
+func discardWorst(results []RoundResult, discards int) {
+	if discards <= 0 || discards >= len(results) {
+		return
+	}
+
+	order := make([]int, len(results))
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if results[order[i]].Points != results[order[j]].Points {
+			return results[order[i]].Points < results[order[j]].Points
+		}
+		return order[i] > order[j]
+	})
+	for _, idx := range order[:discards] {
+		results[idx].Discarded = true
+	}
+}
+
+// compareStandings orders by points and then by the count of best positions, clubs still tied share the position.
+func compareStandings(a, b *StandingsEntry) int {
+	if a.Points != b.Points {
+		if a.Points > b.Points {
+			return -1
+		}
+		return 1
+	}
+
+	countsA, countsB := positionCounts(a.Results), positionCounts(b.Results)
+	for position := 1; position < max(len(countsA), len(countsB)); position++ {
+		countA, countB := countAt(countsA, position), countAt(countsB, position)
+		if countA != countB {
+			if countA > countB {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func positionCounts(results []RoundResult) []int {
+	counts := make([]int, 1)
+	for _, result := range results {
+		if result.Position <= 0 {
+			continue
+		}
+		for len(counts) <= result.Position {
+			counts = append(counts, 0)
+		}
+		counts[result.Position]++
+	}
+	return counts
+}
+
+func countAt(counts []int, position int) int {
+	if position < len(counts) {
+		return counts[position]
+	}
+	return 0
+}
@@ -0,0 +1,241 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+var (
+	tiran = &Entity{ID: 1, Name: "TIRAN"}
+	mecos = &Entity{ID: 2, Name: "MECOS"}
+	cabo  = &Entity{ID: 3, Name: "CABO DA CRUZ"}
+)
+
+// standing is the part of a [StandingsEntry] checked by the tests.
+type standing struct {
+	position int
+	name     string
+	points   float64
+}
+
+func summarize(entries []StandingsEntry) []standing {
+	summary := make([]standing, len(entries))
+	for idx := range entries {
+		summary[idx] = standing{entries[idx].Position, entries[idx].Name(), entries[idx].Points}
+	}
+	return summary
+}
+
+func classified(club *Entity, branch string, position int, points float64) ClassificationEntry {
+	return ClassificationEntry{
+		Participant: &Participant{Club: club, Crew: Crew{Club: club.Name, Branch: branch}},
+		Status:      STATUS_CLASSIFIED,
+		Position:    position,
+		Points:      points,
+	}
+}
+
+func season(classifications ...*Classification) []Race {
+	rounds := make([]Race, len(classifications))
+	for idx := range rounds {
+		rounds[idx] = Race{ID: int64(10 + idx)}
+	}
+	return rounds
+}
+
+func round(entries ...ClassificationEntry) *Classification {
+	return &Classification{Gender: GENDER_MALE, Category: CATEGORY_ABSOLUT, Entries: entries}
+}
+
+func TestDiscardWorst(t *testing.T) {
+	tests := []struct {
+		name     string
+		points   []float64
+		discards int
+		want     []bool
+	}{
+		{"no discards", []float64{3, 1, 2}, 0, []bool{false, false, false}},
+		{"worst", []float64{3, 1, 2}, 1, []bool{false, true, false}},
+		{"two worst", []float64{3, 1, 2}, 2, []bool{false, true, true}},
+		{"latest on ties", []float64{1, 3, 1}, 1, []bool{false, false, true}},
+		{"missing round", []float64{3, 0, 2}, 1, []bool{false, true, false}},
+		{"as many rounds as discards", []float64{3, 1}, 2, []bool{false, false}},
+		{"fewer rounds than discards", []float64{3}, 2, []bool{false}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := make([]RoundResult, len(test.points))
+			for idx, points := range test.points {
+				results[idx] = RoundResult{Points: points}
+			}
+			discardWorst(results, test.discards)
+
+			discarded := make([]bool, len(results))
+			for idx := range results {
+				discarded[idx] = results[idx].Discarded
+			}
+			if !slices.Equal(discarded, test.want) {
+				t.Errorf("discarded=%v, want %v", discarded, test.want)
+			}
+		})
+	}
+}
+
+func TestCompareStandings(t *testing.T) {
+	entry := func(points float64, positions ...int) *StandingsEntry {
+		results := make([]RoundResult, len(positions))
+		for idx, position := range positions {
+			results[idx] = RoundResult{Position: position}
+		}
+		return &StandingsEntry{Points: points, Results: results}
+	}
+
+	tests := []struct {
+		name string
+		a, b *StandingsEntry
+		want int
+	}{
+		{"more points", entry(5, 2, 2), entry(4, 1, 3), -1},
+		{"fewer points", entry(4, 1, 3), entry(5, 2, 2), 1},
+		{"more wins", entry(4, 1, 3), entry(4, 2, 2), -1},
+		{"more second places", entry(4, 2, 2, 0), entry(4, 2, 3, 3), -1},
+		{"missing round", entry(4, 0, 1), entry(4, 2, 3), -1},
+		{"same positions", entry(4, 1, 3), entry(4, 3, 1), 0},
+		{"no positions", entry(0, 0, 0), entry(0), 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := compareStandings(test.a, test.b); got != test.want {
+				t.Errorf("compareStandings=%d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewStandings(t *testing.T) {
+	tests := []struct {
+		name            string
+		classifications []*Classification
+		discards        int
+		want            []standing
+	}{
+		{
+			name: "points",
+			classifications: []*Classification{
+				round(classified(tiran, "", 1, 3), classified(mecos, "", 2, 2), classified(cabo, "", 3, 1)),
+				round(classified(mecos, "", 1, 3), classified(cabo, "", 2, 2), classified(tiran, "", 3, 1)),
+			},
+			want: []standing{{1, "MECOS", 5}, {2, "TIRAN", 4}, {3, "CABO DA CRUZ", 3}},
+		},
+		{
+			name: "discards",
+			classifications: []*Classification{
+				round(classified(tiran, "", 1, 3), classified(mecos, "", 2, 2), classified(cabo, "", 3, 1)),
+				round(classified(mecos, "", 1, 3), classified(cabo, "", 2, 2), classified(tiran, "", 3, 1)),
+			},
+			discards: 1,
+			// TIRAN and MECOS tie on points and wins, MECOS has a second place
+			want: []standing{{1, "MECOS", 3}, {2, "TIRAN", 3}, {3, "CABO DA CRUZ", 2}},
+		},
+		{
+			name: "fewer rounds than discards",
+			classifications: []*Classification{
+				round(classified(tiran, "", 1, 3), classified(mecos, "", 2, 2)),
+			},
+			discards: 2,
+			want:     []standing{{1, "TIRAN", 3}, {2, "MECOS", 2}},
+		},
+		{
+			name: "shared position",
+			classifications: []*Classification{
+				round(classified(tiran, "", 1, 3), classified(mecos, "", 2, 2), classified(cabo, "", 3, 1)),
+				round(classified(mecos, "", 1, 3), classified(tiran, "", 2, 2), classified(cabo, "", 3, 1)),
+			},
+			want: []standing{{1, "MECOS", 5}, {1, "TIRAN", 5}, {3, "CABO DA CRUZ", 2}},
+		},
+		{
+			name: "missing round",
+			classifications: []*Classification{
+				round(classified(tiran, "", 1, 2), classified(mecos, "", 2, 1)),
+				round(classified(mecos, "", 1, 2)),
+			},
+			discards: 1,
+			// TIRAN discards the round it missed and ties MECOS, which has a second place
+			want: []standing{{1, "MECOS", 2}, {2, "TIRAN", 2}},
+		},
+		{
+			name: "round without classification",
+			classifications: []*Classification{
+				round(classified(tiran, "", 1, 2), classified(mecos, "", 2, 1)),
+				nil,
+			},
+			want: []standing{{1, "TIRAN", 2}, {2, "MECOS", 1}},
+		},
+		{
+			name: "branch crews",
+			classifications: []*Classification{
+				round(classified(tiran, "", 1, 3), classified(tiran, "B", 2, 2), classified(mecos, "", 3, 1)),
+				round(classified(tiran, "B", 1, 3), classified(mecos, "", 2, 2), classified(tiran, "", 3, 1)),
+			},
+			want: []standing{{1, "TIRAN B", 5}, {2, "TIRAN", 4}, {3, "MECOS", 3}},
+		},
+		{
+			name: "not classified",
+			classifications: []*Classification{
+				round(
+					classified(tiran, "", 1, 2),
+					ClassificationEntry{Participant: &Participant{Club: mecos}, Status: STATUS_GUEST},
+					ClassificationEntry{Participant: &Participant{Club: cabo}, Status: STATUS_DISQUALIFIED},
+				),
+			},
+			want: []standing{{1, "TIRAN", 2}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries := NewStandings(season(test.classifications...), test.classifications, test.discards)
+			if got := summarize(entries); !slices.Equal(got, test.want) {
+				t.Errorf("standings=%v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewStandingsMissingRound(t *testing.T) {
+	classifications := []*Classification{
+		round(classified(tiran, "", 1, 2), classified(mecos, "", 2, 1)),
+		round(classified(mecos, "", 1, 2)),
+	}
+	entries := NewStandings(season(classifications...), classifications, 1)
+
+	idx := slices.IndexFunc(entries, func(entry StandingsEntry) bool { return entry.Club == tiran })
+	if idx < 0 {
+		t.Fatalf("TIRAN not in the standings")
+	}
+	want := []RoundResult{{RaceID: 10, Position: 1, Points: 2}, {RaceID: 11, Discarded: true}}
+	if got := entries[idx].Results; !slices.Equal(got, want) {
+		t.Errorf("results=%v, want %v", got, want)
+	}
+}
+
+func TestNewStandingsProgression(t *testing.T) {
+	classifications := []*Classification{
+		round(classified(tiran, "", 1, 3), classified(mecos, "", 2, 2), classified(cabo, "", 3, 1)),
+		round(classified(mecos, "", 1, 3), classified(cabo, "", 2, 2), classified(tiran, "", 3, 1)),
+		round(classified(cabo, "", 1, 3), classified(mecos, "", 2, 2), classified(tiran, "", 3, 1)),
+	}
+	rounds := season(classifications...)
+
+	// the standings after each round, the discard only applies once there are more rounds than discards
+	want := [][]standing{
+		{{1, "TIRAN", 3}, {2, "MECOS", 2}, {3, "CABO DA CRUZ", 1}},
+		{{1, "MECOS", 3}, {2, "TIRAN", 3}, {3, "CABO DA CRUZ", 2}},
+		{{1, "MECOS", 5}, {2, "CABO DA CRUZ", 5}, {3, "TIRAN", 4}},
+	}
+	for idx := range rounds {
+		entries := NewStandings(rounds[:idx+1], classifications[:idx+1], 1)
+		if got := summarize(entries); !slices.Equal(got, want[idx]) {
+			t.Errorf("round=%d standings=%v, want %v", idx+1, got, want[idx])
+		}
+	}
+}
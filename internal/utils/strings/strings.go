@@ -1,8 +1,16 @@
 package utils
 
 import (
+	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
 func Min(a, b, c int) int {
@@ -83,3 +91,85 @@ func IntSlice2String(slice []int) string {
 	}
 	return strings.TrimSuffix(str, ",")
 }
+
+var unaccentTransformer = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// Unaccent removes the diacritics from the given string, the same way PostgreSQL's unaccent does for latin scripts.
+func Unaccent(s string) string {
+	result, _, err := transform.String(unaccentTransformer, s)
+	if err != nil {
+		return s
+	}
+	return result
+}
+
+// Trigrams returns the set of trigrams of the given string following pg_trgm rules: the string is lowercased, split
+// in words of alphanumeric characters and each word is padded with two spaces in front and one at the end.
+func Trigrams(s string) map[string]bool {
+	trigrams := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			trigrams[string(padded[i:i+3])] = true
+		}
+	}
+	return trigrams
+}
+
+// WordSimilarity returns the fraction of the query trigrams found in the target, ignoring accents. It approximates
+// pg_trgm's word_similarity: 1 when all the query words appear in the target and 0 when they share nothing.
+func WordSimilarity(query, target string) float64 {
+	queryTrigrams := Trigrams(Unaccent(query))
+	if len(queryTrigrams) == 0 {
+		return 0
+	}
+
+	targetTrigrams := Trigrams(Unaccent(target))
+	shared := 0
+	for trigram := range queryTrigrams {
+		if targetTrigrams[trigram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(queryTrigrams))
+}
+
+// ParseInterval parses the default PostgreSQL output for intervals: "[N day[s] ]HH:MM:SS[.ffffff]".
+func ParseInterval(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	var days int64
+	if idx := strings.Index(value, "day"); idx >= 0 {
+		parsedDays, err := strconv.ParseInt(strings.TrimSpace(value[:idx]), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid interval days: %s", value)
+		}
+		days = parsedDays
+		value = strings.TrimSpace(strings.TrimLeft(value[idx:], "days"))
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid interval: %s", value)
+	}
+	hours, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval hours: %s", value)
+	}
+	minutes, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval minutes: %s", value)
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval seconds: %s", value)
+	}
+
+	return time.Duration(days)*24*time.Hour +
+		time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(math.Round(seconds*float64(time.Second))), nil
+}
@@ -0,0 +1,284 @@
+package outliers
+
+import (
+	"math"
+	"sort"
+
+	"github.com/iagocanalejas/rstats/internal/types"
+)
+
+const (
+	IQR_MIN_PARTICIPANTS  = 4 // quartiles of smaller races are meaningless
+	MAD_MIN_PARTICIPANTS  = 3
+	CONSISTENCY_MIN_RACES = 5
+
+	// scales the median absolute deviation to the standard deviation of a normal distribution
+	MAD_SCALE = 0.6745
+)
+
+// MeanDetector flags the speeds further than a relative threshold from the race mean.
+type MeanDetector struct {
+	Threshold float64
+}
+
+func (d *MeanDetector) Name() string { return MEAN }
+
+func (d *MeanDetector) Detect(races [][]*types.Participant) []Outlier {
+	return detectByRace(races, func(race []*types.Participant) []Outlier {
+		mean := meanOf(speedsOf(race))
+		outliers := make([]Outlier, 0)
+		for _, participant := range race {
+			if *participant.Speed > mean*(1+d.Threshold) || *participant.Speed < mean*(1-d.Threshold) {
+				outliers = append(outliers, newOutlier(MEAN, participant, mean, *participant.Speed/mean-1))
+			}
+		}
+		return outliers
+	})
+}
+
+// LimitsDetector flags the speeds out of the possible range of a rowing boat.
+type LimitsDetector struct {
+	Min, Max float64
+}
+
+func (d *LimitsDetector) Name() string { return LIMITS }
+
+func (d *LimitsDetector) Detect(races [][]*types.Participant) []Outlier {
+	return detectByRace(races, func(race []*types.Participant) []Outlier {
+		outliers := make([]Outlier, 0)
+		for _, participant := range race {
+			switch {
+			case *participant.Speed < d.Min:
+				outliers = append(outliers, newOutlier(LIMITS, participant, d.Min, *participant.Speed-d.Min))
+			case *participant.Speed > d.Max:
+				outliers = append(outliers, newOutlier(LIMITS, participant, d.Max, *participant.Speed-d.Max))
+			}
+		}
+		return outliers
+	})
+}
+
+// MADDetector flags the speeds with a modified z-score, based on the race median and median absolute deviation,
+// above the threshold. Unlike the mean, the median is not dragged by the outlier itself in small fields.
+type MADDetector struct {
+	Threshold float64
+}
+
+func (d *MADDetector) Name() string { return MAD }
+
+func (d *MADDetector) Detect(races [][]*types.Participant) []Outlier {
+	return detectByRace(races, func(race []*types.Participant) []Outlier {
+		outliers := make([]Outlier, 0)
+		if len(race) < MAD_MIN_PARTICIPANTS {
+			return outliers
+		}
+
+		speeds := speedsOf(race)
+		median, mad := medianOf(speeds), madOf(speeds)
+		if mad == 0 {
+			return outliers
+		}
+		for _, participant := range race {
+			if score := MAD_SCALE * (*participant.Speed - median) / mad; math.Abs(score) > d.Threshold {
+				outliers = append(outliers, newOutlier(MAD, participant, median, score))
+			}
+		}
+		return outliers
+	})
+}
+
+// IQRDetector flags the speeds outside the Tukey fences of the race: Factor interquartile ranges below the first
+// quartile or above the third one.
+type IQRDetector struct {
+	Factor float64
+}
+
+func (d *IQRDetector) Name() string { return IQR }
+
+func (d *IQRDetector) Detect(races [][]*types.Participant) []Outlier {
+	return detectByRace(races, func(race []*types.Participant) []Outlier {
+		outliers := make([]Outlier, 0)
+		if len(race) < IQR_MIN_PARTICIPANTS {
+			return outliers
+		}
+
+		speeds := speedsOf(race)
+		sort.Float64s(speeds)
+		q1, median, q3 := quantileOf(speeds, 0.25), quantileOf(speeds, 0.5), quantileOf(speeds, 0.75)
+		iqr := q3 - q1
+		if iqr == 0 {
+			return outliers
+		}
+		lower, upper := q1-d.Factor*iqr, q3+d.Factor*iqr
+		for _, participant := range race {
+			switch {
+			case *participant.Speed < lower:
+				outliers = append(outliers, newOutlier(IQR, participant, median, (*participant.Speed-q1)/iqr))
+			case *participant.Speed > upper:
+				outliers = append(outliers, newOutlier(IQR, participant, median, (*participant.Speed-q3)/iqr))
+			}
+		}
+		return outliers
+	})
+}
+
+// ZScoreDetector normalizes each speed by its race median and flags the normalized speeds with a z-score above the
+// threshold. The mean and deviation come from all the races of the same gender and category, so races with a few
+// participants are still compared with a meaningful distribution.
+type ZScoreDetector struct {
+	Threshold float64
+}
+
+func (d *ZScoreDetector) Name() string { return ZSCORE }
+
+func (d *ZScoreDetector) Detect(races [][]*types.Participant) []Outlier {
+	normalized := normalizeSpeeds(races)
+
+	byGroup := make(map[[2]string][]float64)
+	for _, entry := range normalized {
+		key := [2]string{entry.participant.Gender, entry.participant.Category}
+		byGroup[key] = append(byGroup[key], entry.relative)
+	}
+	type stats struct{ mean, deviation float64 }
+	groups := make(map[[2]string]stats, len(byGroup))
+	for key, values := range byGroup {
+		groups[key] = stats{mean: meanOf(values), deviation: deviationOf(values)}
+	}
+
+	outliers := make([]Outlier, 0)
+	for _, entry := range normalized {
+		group := groups[[2]string{entry.participant.Gender, entry.participant.Category}]
+		if group.deviation == 0 {
+			continue
+		}
+		if score := (entry.relative - group.mean) / group.deviation; math.Abs(score) > d.Threshold {
+			outliers = append(outliers, newOutlier(ZSCORE, entry.participant, entry.median, score))
+		}
+	}
+	sortOutliers(outliers)
+	return outliers
+}
+
+// ConsistencyDetector compares each result of a club with its other races. Speeds are normalized by the race median
+// and a result is flagged when its modified z-score within the club results is above the threshold, catching times
+// that are plausible for the race but not for the crew.
+type ConsistencyDetector struct {
+	Threshold float64
+	MinRaces  int // clubs with fewer races are not checked
+}
+
+func (d *ConsistencyDetector) Name() string { return CONSISTENCY }
+
+func (d *ConsistencyDetector) Detect(races [][]*types.Participant) []Outlier {
+	type clubKey struct {
+		clubID           int64
+		gender, category string
+	}
+
+	byClub := make(map[clubKey][]normalizedSpeed)
+	for _, entry := range normalizeSpeeds(races) {
+		key := clubKey{entry.participant.Club.ID, entry.participant.Gender, entry.participant.Category}
+		byClub[key] = append(byClub[key], entry)
+	}
+
+	outliers := make([]Outlier, 0)
+	for _, entries := range byClub {
+		if len(entries) < max(d.MinRaces, MAD_MIN_PARTICIPANTS) {
+			continue
+		}
+
+		values := make([]float64, len(entries))
+		for idx, entry := range entries {
+			values[idx] = entry.relative
+		}
+		median, mad := medianOf(values), madOf(values)
+		if mad == 0 {
+			continue
+		}
+		for _, entry := range entries {
+			if score := MAD_SCALE * (entry.relative - median) / mad; math.Abs(score) > d.Threshold {
+				// the expected speed is the usual relative speed of the club in this race
+				outliers = append(outliers, newOutlier(CONSISTENCY, entry.participant, median*entry.median, score))
+			}
+		}
+	}
+	sortOutliers(outliers)
+	return outliers
+}
+
+// normalizedSpeed is a speed relative to the median speed of its race.
+type normalizedSpeed struct {
+	participant *types.Participant
+	median      float64
+	relative    float64
+}
+
+func normalizeSpeeds(races [][]*types.Participant) []normalizedSpeed {
+	normalized := make([]normalizedSpeed, 0)
+	for _, race := range races {
+		if len(race) == 0 {
+			continue
+		}
+		median := medianOf(speedsOf(race))
+		if median == 0 {
+			continue
+		}
+		for _, participant := range race {
+			normalized = append(normalized, normalizedSpeed{participant: participant, median: median, relative: *participant.Speed / median})
+		}
+	}
+	return normalized
+}
+
+func speedsOf(race []*types.Participant) []float64 {
+	speeds := make([]float64, len(race))
+	for idx, participant := range race {
+		speeds[idx] = *participant.Speed
+	}
+	return speeds
+}
+
+func meanOf(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func deviationOf(values []float64) float64 {
+	mean := meanOf(values)
+	var sum float64
+	for _, value := range values {
+		sum += (value - mean) * (value - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+func medianOf(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	return quantileOf(sorted, 0.5)
+}
+
+// madOf is the median absolute deviation from the median.
+func madOf(values []float64) float64 {
+	median := medianOf(values)
+	deviations := make([]float64, len(values))
+	for idx, value := range values {
+		deviations[idx] = math.Abs(value - median)
+	}
+	return medianOf(deviations)
+}
+
+// quantileOf interpolates the quantile of sorted values.
+func quantileOf(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
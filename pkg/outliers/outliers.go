@@ -0,0 +1,200 @@
+package outliers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/iagocanalejas/rstats/internal/types"
+)
+
+const (
+	// DETECTORS
+	MEAN        = "mean"
+	LIMITS      = "limits"
+	MAD         = "mad"
+	IQR         = "iqr"
+	ZSCORE      = "zscore"
+	CONSISTENCY = "consistency"
+
+	// COMBINATION MODES
+	ANY = "any" // flagged by any of the detectors
+	ALL = "all" // flagged by all the detectors
+
+	BATCH_SIZE = 500 // races processed by each goroutine of the per race detectors
+)
+
+var ErrInvalidDetector = errors.New("invalid detector")
+
+// Outlier is a participant speed flagged by a detector. Expected is the reference speed the detector compared with
+// and Score how far the speed is from it, in the units of the detector.
+type Outlier struct {
+	RaceID        int64   `json:"race_id"`
+	ParticipantID int64   `json:"participant_id"`
+	ClubID        int64   `json:"club_id"`
+	Speed         float64 `json:"speed"`
+	Expected      float64 `json:"expected"`
+	Score         float64 `json:"score"`
+
+	Detectors []string `json:"detectors"`
+}
+
+// Detector finds the outliers in a list of races. Each race is a group of participants with speed sharing race, gender
+// and category, as returned by [service.Service.GetParticipantsWithSpeed].
+type Detector interface {
+	Name() string
+	Detect(races [][]*types.Participant) []Outlier
+}
+
+// Config holds the thresholds of all the detectors, only the ones of the selected detectors are used.
+type Config struct {
+	MeanThreshold        float64 // relative distance to the race mean
+	MinSpeed, MaxSpeed   float64 // speed limits in km/h
+	MADThreshold         float64 // modified z-score using the race median absolute deviation
+	IQRFactor            float64 // interquartile ranges outside the race quartiles
+	ZThreshold           float64 // z-score of the speeds normalized by their race median
+	ConsistencyThreshold float64 // modified z-score within the normalized speeds of the club
+}
+
+// DefaultConfig returns the usual thresholds, the mean threshold and the limits are the ones cmd/outlier used.
+func DefaultConfig() *Config {
+	return &Config{
+		MeanThreshold:        0.3,
+		MinSpeed:             8.0,
+		MaxSpeed:             20.0,
+		MADThreshold:         3.5,
+		IQRFactor:            1.5,
+		ZThreshold:           3.0,
+		ConsistencyThreshold: 3.5,
+	}
+}
+
+// New builds the detectors by name and combines them with the given mode.
+func New(names []string, mode string, config *Config) (Detector, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: no detectors", ErrInvalidDetector)
+	}
+
+	detectors := make([]Detector, len(names))
+	for idx, name := range names {
+		switch name {
+		case MEAN:
+			detectors[idx] = &MeanDetector{Threshold: config.MeanThreshold}
+		case LIMITS:
+			detectors[idx] = &LimitsDetector{Min: config.MinSpeed, Max: config.MaxSpeed}
+		case MAD:
+			detectors[idx] = &MADDetector{Threshold: config.MADThreshold}
+		case IQR:
+			detectors[idx] = &IQRDetector{Factor: config.IQRFactor}
+		case ZSCORE:
+			detectors[idx] = &ZScoreDetector{Threshold: config.ZThreshold}
+		case CONSISTENCY:
+			detectors[idx] = &ConsistencyDetector{Threshold: config.ConsistencyThreshold, MinRaces: CONSISTENCY_MIN_RACES}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrInvalidDetector, name)
+		}
+	}
+
+	if len(detectors) == 1 {
+		return detectors[0], nil
+	}
+	return Combine(mode, detectors...)
+}
+
+// Combine merges the outliers of several detectors. In [ANY] mode a participant flagged by any detector is an outlier,
+// in [ALL] mode it has to be flagged by every detector. Expected and Score are the ones of the first detector.
+func Combine(mode string, detectors ...Detector) (Detector, error) {
+	if mode != ANY && mode != ALL {
+		return nil, fmt.Errorf("%w: invalid combination mode=%s", ErrInvalidDetector, mode)
+	}
+	return &combined{mode: mode, detectors: detectors}, nil
+}
+
+type combined struct {
+	mode      string
+	detectors []Detector
+}
+
+func (c *combined) Name() string {
+	names := make([]string, len(c.detectors))
+	for idx, detector := range c.detectors {
+		names[idx] = detector.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+func (c *combined) Detect(races [][]*types.Participant) []Outlier {
+	merged := make(map[int64]*Outlier)
+	for _, detector := range c.detectors {
+		for _, outlier := range detector.Detect(races) {
+			if current, ok := merged[outlier.ParticipantID]; ok {
+				current.Detectors = append(current.Detectors, outlier.Detectors...)
+				continue
+			}
+			merged[outlier.ParticipantID] = &outlier
+		}
+	}
+
+	outliers := make([]Outlier, 0, len(merged))
+	for _, outlier := range merged {
+		if c.mode == ALL && len(outlier.Detectors) < len(c.detectors) {
+			continue
+		}
+		outliers = append(outliers, *outlier)
+	}
+	sortOutliers(outliers)
+	return outliers
+}
+
+// raceDetector finds the outliers of a single race.
+type raceDetector func(race []*types.Participant) []Outlier
+
+// detectByRace runs a per race detector over all the races in batches of [BATCH_SIZE] races.
+func detectByRace(races [][]*types.Participant, detect raceDetector) []Outlier {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	outliers := make([]Outlier, 0)
+
+	for i := 0; i < len(races); i += BATCH_SIZE {
+		wg.Add(1)
+		go func(batch [][]*types.Participant) {
+			defer wg.Done()
+
+			found := make([]Outlier, 0)
+			for _, race := range batch {
+				found = append(found, detect(race)...)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			outliers = append(outliers, found...)
+		}(races[i:min(i+BATCH_SIZE, len(races))])
+	}
+
+	wg.Wait()
+	sortOutliers(outliers)
+	return outliers
+}
+
+func newOutlier(detector string, participant *types.Participant, expected, score float64) Outlier {
+	return Outlier{
+		RaceID:        participant.RaceID,
+		ParticipantID: participant.ID,
+		ClubID:        participant.Club.ID,
+		Speed:         *participant.Speed,
+		Expected:      expected,
+		Score:         score,
+		Detectors:     []string{detector},
+	}
+}
+
+func sortOutliers(outliers []Outlier) {
+	sort.Slice(outliers, func(i, j int) bool {
+		if outliers[i].RaceID != outliers[j].RaceID {
+			return outliers[i].RaceID < outliers[j].RaceID
+		}
+		return outliers[i].ParticipantID < outliers[j].ParticipantID
+	})
+}
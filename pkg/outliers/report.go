@@ -0,0 +1,152 @@
+package outliers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iagocanalejas/rstats/internal/types"
+)
+
+const (
+	// REPORT FORMATS
+	FORMAT_JSON     = "json"
+	FORMAT_CSV      = "csv"
+	FORMAT_MARKDOWN = "markdown"
+)
+
+// ReportEntry is an outlier with the details needed to review it.
+type ReportEntry struct {
+	RaceID        int64    `json:"race_id"`
+	RaceName      string   `json:"race_name"`
+	Date          string   `json:"date"`
+	ParticipantID int64    `json:"participant_id"`
+	ClubID        int64    `json:"club_id"`
+	Club          string   `json:"club"`
+	Laps          []string `json:"laps"`
+	Speed         float64  `json:"speed"`
+	RaceMean      float64  `json:"race_mean"` // mean speed of the participants of the race, gender and category
+	Expected      float64  `json:"expected"`
+	Score         float64  `json:"score"`
+	Detectors     []string `json:"detectors"`
+	Review        string   `json:"review"`
+}
+
+// NewReport joins the outliers with their participants, the races they were detected in and the race names. A nil
+// reviews marks every outlier as pending.
+func NewReport(outliers []Outlier, races [][]*types.Participant, raceNames map[int64]string, reviews *Reviews) []ReportEntry {
+	participants := make(map[int64]*types.Participant)
+	means := make(map[int64]float64) // participant ID to the mean of its race
+	for _, race := range races {
+		mean := meanOf(speedsOf(race))
+		for _, participant := range race {
+			participants[participant.ID] = participant
+			means[participant.ID] = mean
+		}
+	}
+
+	entries := make([]ReportEntry, 0, len(outliers))
+	for _, outlier := range outliers {
+		entry := ReportEntry{
+			RaceID:        outlier.RaceID,
+			RaceName:      raceNames[outlier.RaceID],
+			ParticipantID: outlier.ParticipantID,
+			ClubID:        outlier.ClubID,
+			Laps:          make([]string, 0),
+			Speed:         outlier.Speed,
+			RaceMean:      means[outlier.ParticipantID],
+			Expected:      outlier.Expected,
+			Score:         outlier.Score,
+			Detectors:     outlier.Detectors,
+			Review:        REVIEW_PENDING,
+		}
+		if participant, ok := participants[outlier.ParticipantID]; ok {
+			entry.Club = participant.Club.Name
+			if participant.RaceDate != nil {
+				entry.Date = participant.RaceDate.Format(time.DateOnly)
+			}
+			for _, lap := range participant.Laps {
+				entry.Laps = append(entry.Laps, types.FormatLapTime(lap))
+			}
+		}
+		if reviews != nil {
+			entry.Review = reviews.Status(outlier.ParticipantID)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// WriteReport writes the entries in the given format.
+func WriteReport(w io.Writer, format string, entries []ReportEntry) error {
+	switch format {
+	case FORMAT_JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(entries)
+	case FORMAT_CSV:
+		return writeCSV(w, entries)
+	case FORMAT_MARKDOWN:
+		return writeMarkdown(w, entries)
+	}
+	return fmt.Errorf("invalid report format=%s", format)
+}
+
+var reportHeader = []string{"Race", "Name", "Date", "Participant", "Club", "Laps", "Speed", "Race mean", "Expected", "Score", "Detectors", "Review"}
+
+func reportRow(entry *ReportEntry) []string {
+	return []string{
+		strconv.FormatInt(entry.RaceID, 10),
+		entry.RaceName,
+		entry.Date,
+		strconv.FormatInt(entry.ParticipantID, 10),
+		entry.Club,
+		strings.Join(entry.Laps, " "),
+		fmt.Sprintf("%.2f", entry.Speed),
+		fmt.Sprintf("%.2f", entry.RaceMean),
+		fmt.Sprintf("%.2f", entry.Expected),
+		fmt.Sprintf("%.2f", entry.Score),
+		strings.Join(entry.Detectors, ","),
+		entry.Review,
+	}
+}
+
+func writeCSV(w io.Writer, entries []ReportEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(reportHeader); err != nil {
+		return err
+	}
+	for idx := range entries {
+		if err := writer.Write(reportRow(&entries[idx])); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeMarkdown(w io.Writer, entries []ReportEntry) error {
+	separators := make([]string, len(reportHeader))
+	for idx := range separators {
+		separators[idx] = "---"
+	}
+
+	lines := []string{markdownRow(reportHeader), markdownRow(separators)}
+	for idx := range entries {
+		lines = append(lines, markdownRow(reportRow(&entries[idx])))
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func markdownRow(columns []string) string {
+	escaped := make([]string, len(columns))
+	for idx, column := range columns {
+		escaped[idx] = strings.ReplaceAll(column, "|", "\\|")
+	}
+	return "| " + strings.Join(escaped, " | ") + " |"
+}
@@ -0,0 +1,103 @@
+package outliers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	// REVIEW STATUSES
+	REVIEW_PENDING = "pending"
+	REVIEW_ERROR   = "confirmed-error" // the speed is wrong and is excluded from the normalized speeds
+	REVIEW_LEGIT   = "legit"           // the speed is right and is no longer reported
+
+	DEFAULT_REVIEWS_FILE = "outlier-reviews.json"
+)
+
+var ErrInvalidReview = errors.New("invalid review")
+
+// Review is the decision taken over an outlier participant.
+type Review struct {
+	ParticipantID int64     `json:"participant_id"`
+	Status        string    `json:"status"`
+	ReviewedAt    time.Time `json:"reviewed_at"`
+}
+
+// Reviews are the outlier decisions persisted in a JSON file so they are kept across runs.
+type Reviews struct {
+	path    string
+	reviews map[int64]Review
+}
+
+// LoadReviews reads the reviews file, a missing file has no reviews yet.
+func LoadReviews(path string) (*Reviews, error) {
+	r := &Reviews{path: path, reviews: make(map[int64]Review)}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading reviews file=%s: %w", path, err)
+	}
+
+	var reviews []Review
+	if err = json.Unmarshal(content, &reviews); err != nil {
+		return nil, fmt.Errorf("parsing reviews file=%s: %w", path, err)
+	}
+	for _, review := range reviews {
+		r.reviews[review.ParticipantID] = review
+	}
+	return r, nil
+}
+
+// Save writes the reviews ordered by participant.
+func (r *Reviews) Save() error {
+	reviews := make([]Review, 0, len(r.reviews))
+	for _, review := range r.reviews {
+		reviews = append(reviews, review)
+	}
+	sort.Slice(reviews, func(i, j int) bool { return reviews[i].ParticipantID < reviews[j].ParticipantID })
+
+	content, err := json.MarshalIndent(reviews, "", "\t")
+	if err != nil {
+		return fmt.Errorf("encoding reviews: %w", err)
+	}
+	if err = os.WriteFile(r.path, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing reviews file=%s: %w", r.path, err)
+	}
+	return nil
+}
+
+// Mark records the decision over a participant, replacing any previous one.
+func (r *Reviews) Mark(participantID int64, status string) error {
+	if status != REVIEW_ERROR && status != REVIEW_LEGIT {
+		return fmt.Errorf("%w: status=%s", ErrInvalidReview, status)
+	}
+	r.reviews[participantID] = Review{ParticipantID: participantID, Status: status, ReviewedAt: time.Now().UTC()}
+	return nil
+}
+
+// Status returns the decision over a participant, [REVIEW_PENDING] when it was not reviewed.
+func (r *Reviews) Status(participantID int64) string {
+	if review, ok := r.reviews[participantID]; ok {
+		return review.Status
+	}
+	return REVIEW_PENDING
+}
+
+// ConfirmedErrors returns the participants whose speed was confirmed as wrong.
+func (r *Reviews) ConfirmedErrors() []int64 {
+	ids := make([]int64, 0)
+	for id, review := range r.reviews {
+		if review.Status == REVIEW_ERROR {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,170 @@
+package plotter
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"strconv"
+
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+)
+
+const (
+	DEFAULT_BINS = 20
+	KDE_POINTS   = 200  // points where the kernel density estimate is evaluated
+	FILL_ALPHA   = 0x60 // transparency of the overlaid histograms
+)
+
+// histogram overlays the speed distribution of each year and series sharing the same bins. Several distributions,
+// or a density overlay, are drawn as densities so their areas can be compared, a single one as participant counts.
+func histogram(label string, all []series, years []int, config *PlotConfig) error {
+	prettylog.Info("histogramming")
+	p := plot.New()
+
+	type distribution struct {
+		name   string
+		speeds []float64
+		color  color.Color
+	}
+
+	distributions := make([]distribution, 0)
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	for _, s := range all {
+		for _, year := range years {
+			speeds := s.Data[year]
+			if len(speeds) == 0 {
+				continue
+			}
+
+			name := strconv.Itoa(year)
+			if s.Name != "" {
+				name = fmt.Sprintf("%s %s", name, s.Name)
+			}
+			// the colour of the series only tells the distributions apart when there is a single year
+			var c color.Color
+			if len(years) == 1 {
+				c = s.Color
+			}
+			distributions = append(distributions, distribution{name: name, speeds: speeds, color: c})
+			lowest, highest = min(lowest, slices.Min(speeds)), max(highest, slices.Max(speeds))
+		}
+	}
+	if len(distributions) == 0 {
+		return fmt.Errorf("no speeds to plot")
+	}
+
+	bins := config.Bins
+	if bins <= 0 {
+		bins = DEFAULT_BINS
+	}
+	width := (highest - lowest) / float64(bins)
+	if width == 0 {
+		width = 1
+	}
+
+	density := config.Density || len(distributions) > 1
+	for idx, d := range distributions {
+		h := &plotter.Histogram{Bins: make([]plotter.HistogramBin, bins), Width: width, LineStyle: plotter.DefaultLineStyle}
+		for bin := range h.Bins {
+			h.Bins[bin].Min = lowest + float64(bin)*width
+			h.Bins[bin].Max = lowest + float64(bin+1)*width
+		}
+		for _, speed := range d.speeds {
+			h.Bins[min(int((speed-lowest)/width), bins-1)].Weight++
+		}
+		if density {
+			for bin := range h.Bins {
+				h.Bins[bin].Weight /= float64(len(d.speeds)) * width
+			}
+		}
+
+		c := d.color
+		if c == nil {
+			c = plotutil.Color(idx)
+		}
+		if len(distributions) > 1 {
+			r, g, b, _ := c.RGBA()
+			h.FillColor = color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: FILL_ALPHA}
+			h.LineStyle.Color = c
+		} else {
+			h.FillColor = c
+		}
+		p.Add(h)
+
+		if !config.Density {
+			p.Legend.Add(d.name, h)
+			continue
+		}
+
+		line, err := plotter.NewLine(kde(d.speeds, lowest, highest))
+		if err != nil {
+			return fmt.Errorf("generating density for %s: %w", d.name, err)
+		}
+		line.Color = c
+		line.Width = 2 * plotter.DefaultLineStyle.Width
+		p.Add(line)
+		p.Legend.Add(d.name, h, line)
+	}
+
+	p.Title.Text = label
+	p.X.Label.Text = "Velocidades"
+	p.X.Tick.Marker = quarterTicker{}
+	p.Y.Label.Text = "Participantes"
+	if density {
+		p.Y.Label.Text = "Densidad"
+	}
+
+	return displayOrSave(p, config)
+}
+
+// kde estimates the density of the speeds between the limits with a gaussian kernel. The bandwidth follows
+// Silverman's rule of thumb, 0.9 * min(σ, IQR/1.34) * n^(-1/5).
+func kde(speeds []float64, lowest, highest float64) plotter.XYs {
+	sorted := slices.Clone(speeds)
+	slices.Sort(sorted)
+
+	n := float64(len(sorted))
+	var mean, variance float64
+	for _, speed := range sorted {
+		mean += speed / n
+	}
+	for _, speed := range sorted {
+		variance += (speed - mean) * (speed - mean) / n
+	}
+	iqr := quantile(sorted, 0.75) - quantile(sorted, 0.25)
+
+	spread := math.Sqrt(variance)
+	if iqr > 0 {
+		spread = min(spread, iqr/1.34)
+	}
+	bandwidth := 0.9 * spread * math.Pow(n, -0.2)
+	if bandwidth == 0 {
+		bandwidth = 0.1 // all the speeds are the same, keep a visible peak
+	}
+
+	pts := make(plotter.XYs, KDE_POINTS)
+	step := (highest - lowest) / float64(KDE_POINTS-1)
+	for i := range pts {
+		x := lowest + float64(i)*step
+		var y float64
+		for _, speed := range sorted {
+			u := (x - speed) / bandwidth
+			y += math.Exp(-u * u / 2)
+		}
+		pts[i].X = x
+		pts[i].Y = y / (n * bandwidth * math.Sqrt(2*math.Pi))
+	}
+	return pts
+}
+
+// quantile interpolates the q quantile of the sorted values.
+func quantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
@@ -0,0 +1,114 @@
+package plotter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+)
+
+const (
+	// OUTPUT FORMATS
+	FORMAT_PNG = "png"
+	FORMAT_SVG = "svg"
+	FORMAT_PDF = "pdf"
+	FORMAT_EPS = "eps"
+
+	DEFAULT_WIDTH  = 8.0 // inches
+	DEFAULT_HEIGHT = 4.0 // inches
+	DEFAULT_DPI    = 96
+)
+
+var ErrInvalidFormat = errors.New("invalid plot format")
+
+// displayOrSave renders the plot into the output file. Without an output the plot is written to a temporary file and
+// opened with the default viewer, unless headless.
+func displayOrSave(p *plot.Plot, config *PlotConfig) error {
+	format, err := outputFormat(config)
+	if err != nil {
+		return err
+	}
+
+	writer, err := plotWriter(p, config, format)
+	if err != nil {
+		return fmt.Errorf("rendering plot: %w", err)
+	}
+
+	var file *os.File
+	if config.Output != "" {
+		file, err = os.Create(config.Output)
+	} else {
+		file, err = os.CreateTemp("", "rstats-plot-*."+format)
+	}
+	if err != nil {
+		return fmt.Errorf("creating plot file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err = writer.WriteTo(file); err != nil {
+		return fmt.Errorf("saving plot file=%s: %w", file.Name(), err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("saving plot file=%s: %w", file.Name(), err)
+	}
+
+	if config.Output != "" {
+		return nil
+	}
+	if config.Headless {
+		prettylog.Info("plot saved to %s", file.Name())
+		return nil
+	}
+
+	prettylog.Info("opening plot %s", file.Name())
+	return exec.Command("xdg-open", file.Name()).Start()
+}
+
+// outputFormat is the configured format, the extension of the output file or FORMAT_PNG.
+func outputFormat(config *PlotConfig) (string, error) {
+	format := strings.ToLower(config.Format)
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(config.Output)), ".")
+	}
+	if format == "" {
+		format = FORMAT_PNG
+	}
+
+	switch format {
+	case FORMAT_PNG, FORMAT_SVG, FORMAT_PDF, FORMAT_EPS:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrInvalidFormat, format)
+}
+
+// plotWriter draws the plot in a canvas of the format with the configured size. Raster formats use the configured DPI.
+func plotWriter(p *plot.Plot, config *PlotConfig, format string) (io.WriterTo, error) {
+	width, height := vg.Length(DEFAULT_WIDTH)*vg.Inch, vg.Length(DEFAULT_HEIGHT)*vg.Inch
+	if config.Width > 0 {
+		width = vg.Length(config.Width) * vg.Inch
+	}
+	if config.Height > 0 {
+		height = vg.Length(config.Height) * vg.Inch
+	}
+
+	if format == FORMAT_PNG {
+		dpi := DEFAULT_DPI
+		if config.DPI > 0 {
+			dpi = config.DPI
+		}
+		canvas := vgimg.NewWith(vgimg.UseWH(width, height), vgimg.UseDPI(dpi))
+		p.Draw(draw.New(canvas))
+		return vgimg.PngCanvas{Canvas: canvas}, nil
+	}
+
+	return p.WriterTo(width, height, format)
+}
@@ -1,32 +1,40 @@
 package plotter
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"image/color"
 	"math"
-	"os/exec"
+	"slices"
 	"sort"
 	"strconv"
 	"sync"
 
 	"github.com/iagocanalejas/rstats/internal/service"
 	"github.com/iagocanalejas/rstats/internal/types"
-	"github.com/iagocanalejas/rstats/internal/utils/assert"
 	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
 )
 
 const (
 	BOXPLOT   = "boxplot"
 	LINE      = "line"
 	NTH_SPEED = "nth"
+	HISTOGRAM = "histogram"
+	SCATTER   = "scatter"
+	RACE      = "race"
 )
 
 type PlotConfig struct {
 	Index  int
+	RaceID int64 // race plotted in RACE charts
 	Club   *types.Entity
+	Rivals []*types.Entity // clubs compared with Club, each club is plotted as its own series
 	League *types.League
 	Flag   *types.Flag
 
@@ -38,16 +46,37 @@ type PlotConfig struct {
 	Years []int
 	Day   int
 
-	Normalize   bool
-	LeaguesOnly bool
-	BranchTeams bool
+	Normalize      bool
+	Excluded       []int64 // participants left out of the normalized speeds
+	LeaguesOnly    bool
+	Branch         string // one of the types.BRANCH_* modes, BRANCH_SEPARATED plots each crew letter on its own
+	ApplyPenalties bool
 
-	Output string
+	Bins    int  // histogram bins
+	Density bool // overlay a kernel density estimate on the histograms
+
+	Trend string // TREND_NONE, TREND_LINEAR or TREND_LOESS line over the scatter plots
+
+	Splits bool // plot the speed of each lap instead of the gap to the winner in race charts
+
+	Output   string  // file to save the plot, a temporary file is used when empty
+	Format   string  // FORMAT_PNG, FORMAT_SVG, FORMAT_PDF or FORMAT_EPS, taken from the output extension when empty
+	Width    float64 // inches
+	Height   float64 // inches
+	DPI      int     // only used by raster formats
+	Headless bool    // never open a viewer, plots without an output are left in the temporary directory
 }
 
-func PlotStats(s *service.Service, config *PlotConfig) error {
+func PlotStats(ctx context.Context, s *service.Service, config *PlotConfig) error {
 	prettylog.Info("loading data")
-	label := label(config.Index, config.Club, config.League, config.Normalize)
+	if config.PlotType == RACE {
+		return raceplot(ctx, s, config)
+	}
+
+	label := label(config.Index, config.Club, config.Rivals, config.League, config.Normalize)
+	if config.PlotType == SCATTER {
+		return scatterplot(ctx, s, config, label)
+	}
 
 	var years []int
 	var data *map[int][]float64
@@ -58,6 +87,7 @@ func PlotStats(s *service.Service, config *PlotConfig) error {
 
 		var wg sync.WaitGroup
 		var mu sync.Mutex
+		var errs []error
 
 		d := make(map[int][]float64)
 		for _, year := range years {
@@ -67,7 +97,7 @@ func PlotStats(s *service.Service, config *PlotConfig) error {
 				defer wg.Done()
 
 				prettylog.Debug("loading data for year=%d", year)
-				speeds, err := s.GetNthSpeedsBy(&service.GetNthSpeedsByParams{
+				speeds, err := s.GetNthSpeedsBy(ctx, &service.GetNthSpeedsByParams{
 					Index:           config.Index,
 					Club:            config.Club,
 					League:          config.League,
@@ -75,22 +105,34 @@ func PlotStats(s *service.Service, config *PlotConfig) error {
 					Category:        config.Category,
 					Day:             int16(config.Day),
 					Year:            int16(year),
-					BranchTeams:     config.BranchTeams,
+					Branch:          config.Branch,
 					OnlyLeagueRaces: config.LeaguesOnly,
 					Normalize:       config.Normalize,
+					ApplyPenalties:  config.ApplyPenalties,
+					Excluded:        config.Excluded,
 				})
-				assert.NoError(err, "loading data for year=%d with config=%v", year, *config)
 
 				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("loading data for year=%d: %w", year, err))
+					return
+				}
 				d[year] = speeds
-				mu.Unlock()
 			}(year)
 		}
 
 		wg.Wait()
+		if err = errors.Join(errs...); err != nil {
+			return err
+		}
 		data = &d
+	} else if len(config.Rivals) > 0 {
+		return plotClubs(ctx, s, config, label)
+	} else if config.Branch == types.BRANCH_SEPARATED {
+		return plotBranches(ctx, s, config, label)
 	} else {
-		years, data, err = s.GetYearSpeedsBy(&service.GetYearSpeedsByParams{
+		years, data, err = s.GetYearSpeedsBy(ctx, &service.GetYearSpeedsByParams{
 			Club:            config.Club,
 			League:          config.League,
 			Flag:            config.Flag,
@@ -98,40 +140,163 @@ func PlotStats(s *service.Service, config *PlotConfig) error {
 			Category:        config.Category,
 			Day:             int16(config.Day),
 			Years:           config.Years,
-			BranchTeams:     config.BranchTeams,
+			Branch:          config.Branch,
 			OnlyLeagueRaces: config.LeaguesOnly,
 			Normalize:       config.Normalize,
+			ApplyPenalties:  config.ApplyPenalties,
+			Excluded:        config.Excluded,
 		})
-		assert.NoError(err, "loading data with config=%v", *config)
+		if err != nil {
+			return fmt.Errorf("loading data: %w", err)
+		}
 	}
 
+	all := []series{{Data: *data}}
 	switch config.PlotType {
 	case BOXPLOT:
-		return boxplot(label, data, years, config.Output)
+		return boxplot(label, all, years, config)
 	case LINE:
-		return lineplot(label, data, years, config.Output)
+		return lineplot(label, all, years, config)
 	case NTH_SPEED:
-		return lineplot(label, data, years, config.Output)
+		return lineplot(label, all, years, config)
+	case HISTOGRAM:
+		return histogram(label, all, years, config)
 	}
 
 	return nil
 }
 
-func boxplot(label string, data *map[int][]float64, years []int, output string) error {
+// series are the speeds by year of a group of crews plotted together. Charts with several series show them side by
+// side with a legend.
+type series struct {
+	Name  string
+	Data  map[int][]float64
+	Color color.Color // keeps the colour of a series across plots, picked by position when nil
+}
+
+// plotBranches plots the main crews and each branch letter as separate series.
+func plotBranches(ctx context.Context, s *service.Service, config *PlotConfig, label string) error {
+	branches, err := s.GetYearSpeedsByBranch(ctx, &service.GetYearSpeedsByParams{
+		Club:            config.Club,
+		League:          config.League,
+		Flag:            config.Flag,
+		Gender:          config.Gender,
+		Category:        config.Category,
+		Day:             int16(config.Day),
+		Years:           config.Years,
+		OnlyLeagueRaces: config.LeaguesOnly,
+		Normalize:       config.Normalize,
+		ApplyPenalties:  config.ApplyPenalties,
+		Excluded:        config.Excluded,
+	})
+	if err != nil {
+		return fmt.Errorf("loading data: %w", err)
+	}
+
+	all := make([]series, len(branches))
+	years := make([]int, 0)
+	for idx, branch := range branches {
+		all[idx] = series{Name: branch.Branch, Data: *branch.Speeds}
+		for _, year := range branch.Years {
+			if !slices.Contains(years, year) {
+				years = append(years, year)
+			}
+		}
+	}
+	sort.Ints(years)
+
+	switch config.PlotType {
+	case LINE:
+		return lineplot(label, all, years, config)
+	case HISTOGRAM:
+		return histogram(label, all, years, config)
+	}
+	return boxplot(label, all, years, config)
+}
+
+// plotClubs plots the club and each of its rivals as separate series, every club keeps its colour across plots.
+func plotClubs(ctx context.Context, s *service.Service, config *PlotConfig, label string) error {
+	clubs, err := s.GetYearSpeedsByClubs(ctx, &service.GetYearSpeedsByParams{
+		League:          config.League,
+		Flag:            config.Flag,
+		Gender:          config.Gender,
+		Category:        config.Category,
+		Day:             int16(config.Day),
+		Years:           config.Years,
+		Branch:          config.Branch,
+		OnlyLeagueRaces: config.LeaguesOnly,
+		Normalize:       config.Normalize,
+		ApplyPenalties:  config.ApplyPenalties,
+		Excluded:        config.Excluded,
+	}, append([]*types.Entity{config.Club}, config.Rivals...))
+	if err != nil {
+		return fmt.Errorf("loading data: %w", err)
+	}
+
+	all := make([]series, len(clubs))
+	years := make([]int, 0)
+	for idx, club := range clubs {
+		if len(club.Years) == 0 {
+			prettylog.Warning("no speeds found for club=%d (%s)", club.Club.ID, club.Club.Name)
+		}
+		all[idx] = series{Name: club.Club.Name, Data: *club.Speeds, Color: clubColor(club.Club.ID)}
+		for _, year := range club.Years {
+			if !slices.Contains(years, year) {
+				years = append(years, year)
+			}
+		}
+	}
+	sort.Ints(years)
+
+	switch config.PlotType {
+	case LINE:
+		return lineplot(label, all, years, config)
+	case HISTOGRAM:
+		return histogram(label, all, years, config)
+	}
+	return boxplot(label, all, years, config)
+}
+
+func boxplot(label string, all []series, years []int, config *PlotConfig) error {
 	prettylog.Info("boxplotting")
 	p := plot.New()
 
-	boxplotIdx := 0
-	for _, year := range years {
-		speeds := (*data)[year]
-		values := make(plotter.Values, len(speeds))
-		copy(values, speeds)
+	// the boxes of each year are spread around its tick
+	width := 20.0
+	step := 0.0
+	if len(all) > 1 {
+		width = max(6, 40/float64(len(all)))
+		step = 0.8 / float64(len(all))
+	}
 
-		boxplot, err := plotter.NewBoxPlot(vg.Points(20), float64(boxplotIdx), values)
-		assert.NoError(err, "plotting boxplot year=%d values=%v", year, values)
+	for seriesIdx, s := range all {
+		offset := (float64(seriesIdx) - float64(len(all)-1)/2) * step
+		c := s.Color
+		if c == nil {
+			c = plotutil.Color(seriesIdx)
+		}
+		if len(all) > 1 {
+			p.Legend.Add(s.Name, colorThumbnail{color: c})
+		}
 
-		p.Add(boxplot)
-		boxplotIdx++
+		for yearIdx, year := range years {
+			speeds := s.Data[year]
+			if len(speeds) == 0 {
+				continue
+			}
+			values := make(plotter.Values, len(speeds))
+			copy(values, speeds)
+
+			boxplot, err := plotter.NewBoxPlot(vg.Points(width), float64(yearIdx)+offset, values)
+			if err != nil {
+				return fmt.Errorf("plotting boxplot year=%d: %w", year, err)
+			}
+			if len(all) > 1 {
+				boxplot.FillColor = c
+			}
+
+			p.Add(boxplot)
+		}
 	}
 
 	p.Title.Text = label
@@ -140,37 +305,49 @@ func boxplot(label string, data *map[int][]float64, years []int, output string)
 	p.Y.Label.Text = "Velocidades"
 	p.Y.Tick.Marker = quarterTicker{}
 
-	err := displayOrSave(p, output)
-	assert.NoError(err, "saving file")
-	return err
+	return displayOrSave(p, config)
 }
 
-func lineplot(label string, data *map[int][]float64, years []int, output string) error {
+func lineplot(label string, all []series, years []int, config *PlotConfig) error {
 	prettylog.Info("lineplotting")
 	p := plot.New()
 
-	lines := make([]plot.Plotter, len(years))
-	lineplotIdx := 0
+	lines := make([]plot.Plotter, 0, len(years)*len(all))
 	maxValues := 0
-	for _, year := range years {
-		pts := make(plotter.XYs, len((*data)[year]))
-		for i := range (*data)[year] {
-			pts[i].X = float64(i)
-			pts[i].Y = (*data)[year][i]
-		}
-		line, err := plotter.NewLine(pts)
-		assert.NoError(err, "error generating line for year=%d", year)
-
-		line.Color = plotutil.DefaultColors[lineplotIdx]
-		lines[lineplotIdx] = line
-
-		p.Legend.Add(strconv.Itoa(year), line)
-
-		if len((*data)[year]) > maxValues {
-			maxValues = len((*data)[year])
+	for seriesIdx, s := range all {
+		for yearIdx, year := range years {
+			speeds := s.Data[year]
+			if len(all) > 1 && len(speeds) == 0 {
+				continue
+			}
+
+			pts := make(plotter.XYs, len(speeds))
+			for i := range speeds {
+				pts[i].X = float64(i)
+				pts[i].Y = speeds[i]
+			}
+			line, err := plotter.NewLine(pts)
+			if err != nil {
+				return fmt.Errorf("generating line for year=%d: %w", year, err)
+			}
+
+			// years are told apart by colour and series by dashes, unless the series have their own colour
+			line.Color = plotutil.Color(yearIdx)
+			line.Dashes = plotutil.Dashes(seriesIdx)
+			if s.Color != nil {
+				line.Color = s.Color
+				line.Dashes = plotutil.Dashes(yearIdx)
+			}
+			lines = append(lines, line)
+
+			name := strconv.Itoa(year)
+			if s.Name != "" {
+				name = fmt.Sprintf("%s %s", name, s.Name)
+			}
+			p.Legend.Add(name, line)
+
+			maxValues = max(maxValues, len(speeds))
 		}
-
-		lineplotIdx++
 	}
 
 	p.Add(lines...)
@@ -191,31 +368,23 @@ func lineplot(label string, data *map[int][]float64, years []int, output string)
 	p.Y.Label.Text = "Velocidades"
 	p.Y.Tick.Marker = quarterTicker{}
 
-	err := displayOrSave(p, output)
-	assert.NoError(err, "saving file")
-	return err
+	return displayOrSave(p, config)
 }
 
-func displayOrSave(p *plot.Plot, output string) error {
-	if output == "" {
-		filename := "./tmp/_temp_plot.png"
-
-		err := p.Save(8*vg.Inch, 4*vg.Inch, filename)
-		assert.NoError(err, "saving temporal file")
-
-		prettylog.Info("opening plot")
-		return exec.Command("xdg-open", filename).Start()
+func label(index int, club *types.Entity, rivals []*types.Entity, league *types.League, normalized bool) string {
+	var clubs string
+	if club != nil {
+		clubs = club.Name
+		for _, rival := range rivals {
+			clubs = fmt.Sprintf("%s vs %s", clubs, rival.Name)
+		}
 	}
 
-	return p.Save(8*vg.Inch, 4*vg.Inch, output)
-}
-
-func label(index int, club *types.Entity, league *types.League, normalized bool) string {
 	label := "VELOCIDADES (km/h)"
 	if club != nil && league != nil {
-		label = fmt.Sprintf("%s (%s) %s", club.Name, league.Symbol, label)
+		label = fmt.Sprintf("%s (%s) %s", clubs, league.Symbol, label)
 	} else if club != nil {
-		label = fmt.Sprintf("%s %s", club.Name, label)
+		label = fmt.Sprintf("%s %s", clubs, label)
 	} else if league != nil {
 		label = fmt.Sprintf("%s %s", league.Symbol, label)
 	}
@@ -252,8 +421,9 @@ type yearMarker struct{ Years []int }
 
 func (y yearMarker) Ticks(minimum, maximum float64) []plot.Tick {
 	var ticks []plot.Tick
-	for i := minimum; i <= maximum; i++ {
-		year := y.Years[int(i)]
+	// grouped boxes spread around the year so the limits may not be whole
+	for i := max(int(math.Ceil(minimum)), 0); i <= int(math.Floor(maximum)) && i < len(y.Years); i++ {
+		year := y.Years[i]
 		if year%2 == 0 {
 			ticks = append(ticks, plot.Tick{Value: float64(i), Label: fmt.Sprintf("%d", year)})
 		} else {
@@ -263,12 +433,46 @@ func (y yearMarker) Ticks(minimum, maximum float64) []plot.Tick {
 	return ticks
 }
 
+// colorThumbnail is the legend entry of the boxes filled with a colour.
+type colorThumbnail struct{ color color.Color }
+
+func (t colorThumbnail) Thumbnail(c *draw.Canvas) {
+	pts := []vg.Point{{X: c.Min.X, Y: c.Min.Y}, {X: c.Min.X, Y: c.Max.Y}, {X: c.Max.X, Y: c.Max.Y}, {X: c.Max.X, Y: c.Min.Y}}
+	c.FillPolygon(t.color, c.ClipPolygonY(pts))
+}
+
+// clubColor is the colour of a club in the comparisons, derived from its ID so it does not change between plots. Hues
+// are spread with the golden ratio so consecutive IDs get clearly different colours.
+func clubColor(clubID int64) color.Color {
+	const high, low = 191, 0 // fully saturated and dark enough to stand out on white
+
+	hue := math.Mod(float64(clubID)*0.618033988749895, 1) * 6
+	x := uint8(high * (1 - math.Abs(math.Mod(hue, 2)-1)))
+	switch int(hue) {
+	case 0:
+		return color.RGBA{R: high, G: x, B: low, A: 255}
+	case 1:
+		return color.RGBA{R: x, G: high, B: low, A: 255}
+	case 2:
+		return color.RGBA{R: low, G: high, B: x, A: 255}
+	case 3:
+		return color.RGBA{R: low, G: x, B: high, A: 255}
+	case 4:
+		return color.RGBA{R: x, G: low, B: high, A: 255}
+	}
+	return color.RGBA{R: high, G: low, B: x, A: 255}
+}
+
 type keysMarker struct{ Keys []string }
 
 func (k keysMarker) Ticks(minimum, maximum float64) []plot.Tick {
 	var ticks []plot.Tick
-	for i := minimum; i <= maximum; i++ {
-		ticks = append(ticks, plot.Tick{Value: float64(i), Label: k.Keys[int(i)]})
+	for i := math.Ceil(minimum); i <= maximum; i++ {
+		// the axis may be padded past the keys, i.e. when there is a single point
+		if i < 0 || int(i) >= len(k.Keys) {
+			continue
+		}
+		ticks = append(ticks, plot.Tick{Value: i, Label: k.Keys[int(i)]})
 	}
 	return ticks
 }
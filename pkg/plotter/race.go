@@ -0,0 +1,176 @@
+package plotter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/iagocanalejas/rstats/internal/service"
+	"github.com/iagocanalejas/rstats/internal/types"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// raceplot draws the lap marks of the crews of a race, the gap to the winner at each of them or the speed of each lap
+// with config.Splits. Crews are coloured by series and disqualified ones are crossed, retired crews are drawn until
+// their last lap. Crews without a time for every lap mark are left out.
+func raceplot(ctx context.Context, s *service.Service, config *PlotConfig) error {
+	race, err := s.GetRaceByID(ctx, config.RaceID)
+	if err != nil {
+		return fmt.Errorf("loading race=%d: %w", config.RaceID, err)
+	}
+	classification, err := raceClassification(race, config.Gender, config.Category)
+	if err != nil {
+		return err
+	}
+
+	laps := 0
+	if race.Laps != nil {
+		laps = int(*race.Laps)
+	}
+
+	// the gaps are measured to the fastest crew with every lap mark, the winner unless only its final time is known
+	var reference *types.ClassificationEntry
+	for idx := range classification.Entries {
+		entry := &classification.Entries[idx]
+		if entry.Status != types.STATUS_CLASSIFIED || len(entry.Participant.Laps) == 0 {
+			continue
+		}
+		if laps == 0 {
+			laps = len(entry.Participant.Laps)
+		}
+		if entry.Participant.Laps.Validate(laps) == nil {
+			reference = entry
+			break
+		}
+	}
+	if reference == nil {
+		return fmt.Errorf("race=%d has no classified crew with every lap time", race.ID)
+	}
+	if reference.Position != 1 {
+		prettylog.Warning("the winner of race=%d has no lap times, gaps are measured to position=%d", race.ID, reference.Position)
+	}
+
+	prettylog.Info("plotting race")
+	p := plot.New()
+
+	drawn := 0
+	crews := make(map[int]int) // crews drawn in each series, told apart by dashes
+	for _, entry := range classification.Entries {
+		participant := entry.Participant
+		crewLaps := participant.Laps
+		if len(crewLaps) == 0 {
+			continue
+		}
+		if err := crewLaps.Validate(0); err != nil ||
+			len(crewLaps) > laps ||
+			(len(crewLaps) < laps && entry.Status != types.STATUS_RETIRED) ||
+			(config.Splits && participant.Distance <= 0) {
+			prettylog.Debug("skipping participant=%d laps=%s", participant.ID, crewLaps)
+			continue
+		}
+
+		pts := make(plotter.XYs, 0, laps+1)
+		if config.Splits {
+			lapDistance := float64(participant.Distance) / float64(laps)
+			for idx, split := range crewLaps.Splits() {
+				pts = append(pts, plotter.XY{X: float64(idx + 1), Y: lapDistance / split.Seconds() * 3.6})
+			}
+		} else {
+			pts = append(pts, plotter.XY{X: 0, Y: 0})
+			for idx, lap := range crewLaps {
+				pts = append(pts, plotter.XY{X: float64(idx + 1), Y: (lap - reference.Participant.Laps[idx]).Seconds()})
+			}
+		}
+
+		line, points, err := plotter.NewLinePoints(pts)
+		if err != nil {
+			return fmt.Errorf("plotting participant=%d: %w", participant.ID, err)
+		}
+
+		series := 0
+		if participant.Series != nil {
+			series = int(*participant.Series)
+		}
+		line.Color = plotutil.Color(max(series-1, 0))
+		line.Dashes = plotutil.Dashes(crews[series])
+		points.Color = line.Color
+		points.Shape = draw.CircleGlyph{}
+		points.Radius = vg.Points(2)
+		if entry.Status == types.STATUS_DISQUALIFIED {
+			points.Shape = draw.CrossGlyph{}
+			points.Radius = vg.Points(4)
+		}
+		crews[series]++
+		drawn++
+
+		p.Add(line, points)
+		p.Legend.Add(crewLabel(&entry, series), line, points)
+	}
+
+	if drawn == 0 {
+		return fmt.Errorf("race=%d has no crew with lap times to plot", race.ID)
+	}
+
+	keys := make([]string, laps+1)
+	for lap := 1; lap <= laps; lap++ {
+		keys[lap] = strconv.Itoa(lap)
+	}
+	if !config.Splits {
+		keys[0] = "Salida"
+	}
+
+	p.Title.Text = fmt.Sprintf("%s (%s)", race.Name, race.Date)
+	p.X.Label.Text = "Largo"
+	p.X.Tick.Marker = keysMarker{Keys: keys}
+	if config.Splits {
+		p.Y.Label.Text = "Velocidades"
+		p.Y.Tick.Marker = quarterTicker{}
+	} else {
+		p.Y.Label.Text = fmt.Sprintf("Diferencia con %s (s)", reference.Participant.Club.Name)
+		p.Legend.Top, p.Legend.Left = true, true // gaps grow to the right
+	}
+
+	return displayOrSave(p, config)
+}
+
+// raceClassification is the classification of the race for the gender and category, the only one when the race has a
+// single classification.
+func raceClassification(race *types.Race, gender, category string) (*types.Classification, error) {
+	classifications := types.NewClassifications(race.Participants, nil)
+	if len(classifications) == 1 {
+		return &classifications[0], nil
+	}
+	for idx := range classifications {
+		if classifications[idx].Gender == gender && classifications[idx].Category == category {
+			return &classifications[idx], nil
+		}
+	}
+	return nil, fmt.Errorf("race=%d has no %s %s crews", race.ID, gender, category)
+}
+
+// crewLabel names a crew in the legend with its position, or its status when it is not classified, and its series.
+func crewLabel(entry *types.ClassificationEntry, series int) string {
+	name := entry.Participant.Club.Name
+	if entry.Participant.Crew.IsBranch() {
+		name = fmt.Sprintf("%s %s", name, entry.Participant.Crew.Branch)
+	}
+
+	switch entry.Status {
+	case types.STATUS_CLASSIFIED:
+		name = fmt.Sprintf("%d. %s", entry.Position, name)
+	case types.STATUS_DISQUALIFIED:
+		name = fmt.Sprintf("DSQ %s", name)
+	case types.STATUS_RETIRED:
+		name = fmt.Sprintf("RET %s", name)
+	}
+
+	if series > 0 {
+		name = fmt.Sprintf("%s (tanda %d)", name, series)
+	}
+	return name
+}
@@ -0,0 +1,346 @@
+package plotter
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/iagocanalejas/rstats/internal/service"
+	prettylog "github.com/iagocanalejas/rstats/internal/utils/pretty-log"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+const (
+	// TREND LINES
+	TREND_NONE   = ""
+	TREND_LINEAR = "linear"
+	TREND_LOESS  = "loess"
+
+	LOESS_SPAN   = 0.75 // fraction of the speeds weighted in each local fit
+	TREND_POINTS = 200  // points where the trend line is evaluated
+	CONFIDENCE_Z = 1.96 // normal quantile of the 95% confidence bands
+)
+
+// scatterplot places every speed at the date of its race, coloured by year. When a club is plotted within a league or
+// a flag all the crews are drawn and the speeds of the club are ringed and labelled, the trend follows the club then.
+func scatterplot(ctx context.Context, s *service.Service, config *PlotConfig, label string) error {
+	highlight := config.Club != nil && (config.League != nil || config.Flag != nil)
+
+	club := config.Club
+	if highlight {
+		club = nil
+	}
+	speeds, err := s.GetDatedSpeedsBy(ctx, &service.GetYearSpeedsByParams{
+		Club:            club,
+		League:          config.League,
+		Flag:            config.Flag,
+		Gender:          config.Gender,
+		Category:        config.Category,
+		Day:             int16(config.Day),
+		Years:           config.Years,
+		Branch:          config.Branch,
+		OnlyLeagueRaces: config.LeaguesOnly,
+		Normalize:       config.Normalize,
+		ApplyPenalties:  config.ApplyPenalties,
+		Excluded:        config.Excluded,
+	})
+	if err != nil {
+		return fmt.Errorf("loading data: %w", err)
+	}
+	if len(speeds) == 0 {
+		return fmt.Errorf("no speeds to plot")
+	}
+
+	prettylog.Info("scatterplotting")
+	p := plot.New()
+
+	years := make([]int, 0)
+	byYear := make(map[int]plotter.XYs)
+	clubPts := make(plotter.XYs, 0)
+	clubLabels := make([]string, 0)
+	for _, speed := range speeds {
+		year := speed.Date.Year()
+		if _, ok := byYear[year]; !ok {
+			years = append(years, year)
+		}
+		pt := plotter.XY{X: float64(speed.Date.Unix()), Y: speed.Speed}
+		byYear[year] = append(byYear[year], pt)
+
+		if highlight && speed.ClubID == config.Club.ID {
+			clubPts = append(clubPts, pt)
+			clubLabels = append(clubLabels, fmt.Sprintf("%.2f", speed.Speed))
+		}
+	}
+	slices.Sort(years)
+
+	trendPts := make(plotter.XYs, 0, len(speeds))
+	if highlight {
+		trendPts = clubPts
+	} else {
+		for _, year := range years {
+			trendPts = append(trendPts, byYear[year]...)
+		}
+	}
+	if err := addTrend(p, trendPts, config.Trend); err != nil {
+		return err
+	}
+
+	for idx, year := range years {
+		scatter, err := plotter.NewScatter(byYear[year])
+		if err != nil {
+			return fmt.Errorf("plotting speeds year=%d: %w", year, err)
+		}
+		scatter.GlyphStyle.Color = plotutil.Color(idx)
+		scatter.GlyphStyle.Shape = draw.CircleGlyph{}
+		scatter.GlyphStyle.Radius = vg.Points(2)
+		p.Add(scatter)
+		p.Legend.Add(strconv.Itoa(year), scatter)
+	}
+
+	if len(clubPts) > 0 {
+		rings, err := plotter.NewScatter(clubPts)
+		if err != nil {
+			return fmt.Errorf("plotting club speeds: %w", err)
+		}
+		rings.GlyphStyle.Color = color.Black
+		rings.GlyphStyle.Shape = draw.RingGlyph{}
+		rings.GlyphStyle.Radius = vg.Points(4)
+
+		labels, err := plotter.NewLabels(plotter.XYLabels{XYs: clubPts, Labels: clubLabels})
+		if err != nil {
+			return fmt.Errorf("labelling club speeds: %w", err)
+		}
+		for i := range labels.TextStyle {
+			labels.TextStyle[i].Font.Size = vg.Points(7)
+		}
+		labels.Offset = vg.Point{X: vg.Points(5), Y: vg.Points(2)}
+
+		p.Add(rings, labels)
+		p.Legend.Add(config.Club.Name, rings)
+	}
+
+	p.Title.Text = label
+	p.X.Label.Text = "Fecha"
+	p.X.Tick.Marker = dateTicker{}
+	p.Y.Label.Text = "Velocidades"
+	p.Y.Tick.Marker = quarterTicker{}
+
+	return displayOrSave(p, config)
+}
+
+// addTrend draws the trend of the points with its confidence band, nothing is drawn for TREND_NONE.
+func addTrend(p *plot.Plot, pts plotter.XYs, trend string) error {
+	var fit func(pts plotter.XYs, xs []float64) (plotter.XYs, []float64, error)
+	switch trend {
+	case TREND_NONE:
+		return nil
+	case TREND_LINEAR:
+		fit = linearTrend
+	case TREND_LOESS:
+		fit = loessTrend
+	default:
+		return fmt.Errorf("invalid trend=%s", trend)
+	}
+
+	if len(pts) < 3 {
+		prettylog.Warning("not enough speeds for a %s trend", trend)
+		return nil
+	}
+
+	lowest, highest := pts[0].X, pts[0].X
+	for _, pt := range pts {
+		lowest, highest = min(lowest, pt.X), max(highest, pt.X)
+	}
+	xs := make([]float64, TREND_POINTS)
+	for i := range xs {
+		xs[i] = lowest + float64(i)*(highest-lowest)/float64(TREND_POINTS-1)
+	}
+
+	line, deviations, err := fit(pts, xs)
+	if err != nil {
+		prettylog.Warning("skipping %s trend: %v", trend, err)
+		return nil
+	}
+
+	// the band goes along the upper limits and back along the lower ones
+	band := make(plotter.XYs, 0, 2*len(line))
+	for i, pt := range line {
+		band = append(band, plotter.XY{X: pt.X, Y: pt.Y + CONFIDENCE_Z*deviations[i]})
+	}
+	for i := len(line) - 1; i >= 0; i-- {
+		band = append(band, plotter.XY{X: line[i].X, Y: line[i].Y - CONFIDENCE_Z*deviations[i]})
+	}
+	polygon, err := plotter.NewPolygon(band)
+	if err != nil {
+		return fmt.Errorf("plotting %s trend band: %w", trend, err)
+	}
+	polygon.Color = color.NRGBA{A: FILL_ALPHA / 2}
+	polygon.LineStyle.Width = 0
+
+	l, err := plotter.NewLine(line)
+	if err != nil {
+		return fmt.Errorf("plotting %s trend: %w", trend, err)
+	}
+	l.Color = color.Black
+	l.Width = 2 * plotter.DefaultLineStyle.Width
+
+	p.Add(polygon, l)
+	p.Legend.Add(fmt.Sprintf("tendencia (%s)", trend), l)
+	return nil
+}
+
+// linearTrend fits a least squares line to the points and evaluates it at xs, with the standard error of the fitted
+// mean at each of them.
+func linearTrend(pts plotter.XYs, xs []float64) (plotter.XYs, []float64, error) {
+	n := float64(len(pts))
+	var meanX, meanY float64
+	for _, pt := range pts {
+		meanX += pt.X / n
+		meanY += pt.Y / n
+	}
+
+	var sxx, sxy float64
+	for _, pt := range pts {
+		sxx += (pt.X - meanX) * (pt.X - meanX)
+		sxy += (pt.X - meanX) * (pt.Y - meanY)
+	}
+	if sxx == 0 {
+		return nil, nil, fmt.Errorf("all the speeds are on the same date")
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var sse float64
+	for _, pt := range pts {
+		residual := pt.Y - (intercept + slope*pt.X)
+		sse += residual * residual
+	}
+	sigma := math.Sqrt(sse / (n - 2))
+
+	line := make(plotter.XYs, len(xs))
+	deviations := make([]float64, len(xs))
+	for i, x := range xs {
+		line[i] = plotter.XY{X: x, Y: intercept + slope*x}
+		deviations[i] = sigma * math.Sqrt(1/n+(x-meanX)*(x-meanX)/sxx)
+	}
+	return line, deviations, nil
+}
+
+// loessTrend fits a local linear regression around each of xs weighting the nearest LOESS_SPAN of the points with a
+// tricube kernel. Errors come from the residual variance, as the fit is linear in the speeds.
+func loessTrend(pts plotter.XYs, xs []float64) (plotter.XYs, []float64, error) {
+	n := len(pts)
+
+	// weights are the contribution of each speed to the fit at x
+	weights := func(x float64) []float64 {
+		distances := make([]float64, n)
+		for i, pt := range pts {
+			distances[i] = math.Abs(pt.X - x)
+		}
+		sorted := slices.Clone(distances)
+		slices.Sort(sorted)
+		h := sorted[max(int(math.Ceil(LOESS_SPAN*float64(n)))-1, 0)]
+
+		// speeds of the same race share their distance, widen the bandwidth to the next race so the one at the edge is
+		// weighted, otherwise two close races alone would extrapolate their slope
+		if idx := slices.IndexFunc(sorted, func(d float64) bool { return d > h }); idx >= 0 {
+			h = sorted[idx]
+		} else {
+			h *= 2
+		}
+
+		w := make([]float64, n)
+		var s0, s1, s2 float64
+		for i, d := range distances {
+			if d > h || (d == h && h > 0) {
+				continue
+			}
+			u := 0.0
+			if h > 0 {
+				u = d / h
+			}
+			w[i] = math.Pow(1-u*u*u, 3)
+			dx := pts[i].X - x
+			s0 += w[i]
+			s1 += w[i] * dx
+			s2 += w[i] * dx * dx
+		}
+
+		l := make([]float64, n)
+		denominator := s0*s2 - s1*s1
+		for i := range w {
+			if denominator > 0 {
+				l[i] = w[i] * (s2 - (pts[i].X-x)*s1) / denominator
+			} else {
+				l[i] = w[i] / s0 // every weighted speed is on the same date
+			}
+		}
+		return l
+	}
+
+	// residual variance over the equivalent degrees of freedom, n - trace of the smoother
+	var sse, trace float64
+	for i, pt := range pts {
+		l := weights(pt.X)
+		var fitted float64
+		for j, other := range pts {
+			fitted += l[j] * other.Y
+		}
+		sse += (pt.Y - fitted) * (pt.Y - fitted)
+		trace += l[i]
+	}
+	if float64(n)-trace <= 0 {
+		return nil, nil, fmt.Errorf("not enough speeds to estimate the confidence band")
+	}
+	sigma := math.Sqrt(sse / (float64(n) - trace))
+
+	line := make(plotter.XYs, len(xs))
+	deviations := make([]float64, len(xs))
+	for i, x := range xs {
+		l := weights(x)
+		var fitted, norm float64
+		for j, pt := range pts {
+			fitted += l[j] * pt.Y
+			norm += l[j] * l[j]
+		}
+		line[i] = plotter.XY{X: x, Y: fitted}
+		deviations[i] = sigma * math.Sqrt(norm)
+	}
+	return line, deviations, nil
+}
+
+// dateTicker marks the dates of an axis in unix seconds, by month on short ranges and by year on long ones.
+type dateTicker struct{}
+
+func (t dateTicker) Ticks(minimum, maximum float64) []plot.Tick {
+	start, end := time.Unix(int64(minimum), 0).UTC(), time.Unix(int64(maximum), 0).UTC()
+
+	months, format := 1, "01/2006"
+	if years := end.Year() - start.Year(); years > 3 {
+		months, format = 12, "2006"
+	} else if years > 0 {
+		months = 3
+	}
+
+	current := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if months == 12 {
+		current = time.Date(start.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	var ticks []plot.Tick
+	for ; !current.After(end); current = current.AddDate(0, months, 0) {
+		if current.Before(start) {
+			continue
+		}
+		ticks = append(ticks, plot.Tick{Value: float64(current.Unix()), Label: current.Format(format)})
+	}
+	return ticks
+}
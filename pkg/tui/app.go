@@ -1,7 +1,10 @@
 package tui
 
 import (
+	"context"
+
 	"github.com/gdamore/tcell/v2"
+	"github.com/iagocanalejas/rstats/internal/db"
 	"github.com/iagocanalejas/rstats/internal/service"
 	"github.com/iagocanalejas/rstats/internal/types"
 	"github.com/rivo/tview"
@@ -12,30 +15,55 @@ type Application struct {
 
 	service *service.Service
 
-	race           *types.Race
-	races          []types.Race
-	currentSearch  string // current search keywords
-	hasError       bool   // if the error modal is showing or not
-	showingDetails bool   // if the details view is in display
+	ctx          context.Context    // cancelled when the application stops
+	cancel       context.CancelFunc // stops all in-flight queries
+	cancelSearch context.CancelFunc // cancels the in-flight search
+	searchID     int                // identifies the latest search so outdated results are dropped
+	searching    bool               // if a search is in flight or not
+
+	race            *types.Race
+	classifications []types.Classification
+	races           []types.Race
+	nextPage        *service.RaceCursor // cursor of the next page of races, nil when all were loaded
+	currentSearch   string              // current search keywords
+	listSearch      string              // keywords of the search shown in the list
+	hasError        bool                // if the error modal is showing or not
+	showingDetails  bool                // if the details view is in display
+
+	root             tview.Primitive // view at the root of the application, the error modal aside
+	details          tview.Primitive // details view, restored when leaving the standings
+	showingStandings bool            // if the standings view opened from the details is in display
 
 	flex        *tview.Flex
 	searchInput *tview.InputField
 	racesList   *tview.List
 }
 
-func BuildApp() *Application {
+// BuildApp builds the TUI over the configured database. Race details give league points using the given tables.
+func BuildApp(ctx context.Context, config *db.Config, pointsTables service.PointsTables) (*Application, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s, err := service.Init(ctx, config)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	s.SetPointsTables(pointsTables)
+
 	app := &Application{
 		App:           tview.NewApplication().EnableMouse(true),
-		service:       service.Init(),
+		service:       s,
+		ctx:           ctx,
+		cancel:        cancel,
 		currentSearch: "",
 	}
 
 	app.setupListeners()
 	app.initFlex()
 
-	app.App.SetRoot(app.flex, true)
+	app.setRoot(app.flex)
 
-	return app
+	return app, nil
 }
 
 func (app *Application) initFlex() {
@@ -58,18 +86,42 @@ func (app *Application) setupListeners() {
 			}
 		case tcell.KeyTab:
 			app.nextFocus()
+		case tcell.KeyRune:
+			if app.root == app.details && event.Rune() == 's' {
+				// league standings of the race in details
+				app.showStandingsView()
+				return nil
+			}
 		case tcell.KeyEsc:
-			if app.showingDetails {
-				app.App.SetRoot(app.flex, true)
+			if app.showingStandings {
+				// back to the details the standings were opened from
+				app.setRoot(app.details)
+				app.showingStandings = false
+			} else if app.showingDetails {
+				app.setRoot(app.flex)
 				app.showingDetails = false
+			} else if app.searching {
+				app.cancelSearch()
+				app.searching = false
 			} else {
-				app.App.Stop()
+				app.stop()
 			}
 		}
 		return event
 	})
 }
 
+// setRoot shows the view at the root of the application.
+func (app *Application) setRoot(root tview.Primitive) {
+	app.root = root
+	app.App.SetRoot(root, true)
+}
+
+func (app *Application) stop() {
+	app.cancel()
+	app.App.Stop()
+}
+
 func (app *Application) nextFocus() {
 	if app.searchInput.HasFocus() {
 		app.App.SetFocus(app.racesList)
@@ -87,7 +139,15 @@ func (app *Application) errorModal(err error) {
 		SetText(err.Error()).
 		AddButtons([]string{"Continue"}).
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-			app.App.SetRoot(app.flex, true).SetFocus(app.flex)
+			// errors over the details, i.e. loading its standings, go back to them, any other goes back to the list
+			app.showingStandings = false
+			if app.showingDetails {
+				app.setRoot(app.details)
+				app.App.SetFocus(app.details)
+			} else {
+				app.setRoot(app.flex)
+				app.App.SetFocus(app.flex)
+			}
 			app.hasError = false
 		})
 
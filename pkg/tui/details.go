@@ -2,27 +2,30 @@ package tui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/iagocanalejas/rstats/internal/types"
 	"github.com/rivo/tview"
 )
 
 // TODO: improve this view
 func (app *Application) showDetailsView(raceID int64) {
-	app.showingDetails = true
-	race, err := app.service.GetRaceByID(raceID)
+	race, classifications, err := app.service.GetRaceClassification(app.ctx, raceID)
 	if err != nil {
 		app.errorModal(err)
 		return
 	}
 
 	app.race = race
+	app.classifications = classifications
 
-	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+	app.details = tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(app.detailHeader(), 3, 0, false).
 		AddItem(app.participantDetails(), 0, 1, true)
 
-	app.App.SetRoot(flex, true)
+	app.setRoot(app.details)
+	app.showingDetails = true
 }
 
 func (app *Application) detailHeader() *tview.TextView {
@@ -30,6 +33,9 @@ func (app *Application) detailHeader() *tview.TextView {
 		SetTextColor(tcell.ColorGreen).
 		SetTextAlign(tview.AlignLeft).
 		SetText(fmt.Sprintf("%d (%s) || %s", app.race.ID, app.race.Date, app.race.Name))
+	if app.race.League != nil {
+		header.SetText(header.GetText(false) + " || press 's' for the league standings")
+	}
 
 	header.Box.SetBorder(true)
 
@@ -40,27 +46,96 @@ func (app *Application) participantDetails() *tview.Table {
 	table := tview.NewTable().
 		SetBorders(true)
 
-	table.SetCell(0, 0, &tview.TableCell{Text: "Club Name", Align: tview.AlignCenter, Color: tcell.ColorYellow})
-	table.SetCell(0, 1, &tview.TableCell{Text: "Serie", Align: tview.AlignCenter, Color: tcell.ColorYellow})
-	table.SetCell(0, 2, &tview.TableCell{Text: "Lane", Align: tview.AlignCenter, Color: tcell.ColorYellow})
-	for i := 0; i < int(*app.race.Laps); i++ {
-		colIndex := 3 + i
-		table.SetCell(0, colIndex, &tview.TableCell{Text: fmt.Sprintf("Lap %d", i+1), Align: tview.AlignCenter, Color: tcell.ColorYellow})
+	laps := 0
+	if app.race.Laps != nil {
+		laps = int(*app.race.Laps)
+	}
+
+	headers := []string{"Pos", "Club Name", "Serie", "Lane"}
+	for i := 0; i < laps; i++ {
+		headers = append(headers, fmt.Sprintf("Lap %d", i+1))
 	}
-	table.SetCell(0, int(*app.race.Laps)+2, &tview.TableCell{Text: "Time", Align: tview.AlignCenter, Color: tcell.ColorYellow})
-
-	for i, participant := range app.race.Participants {
-		rowIndex := i + 1
-		table.SetCell(rowIndex, 0, &tview.TableCell{Text: participant.Club.Name, Align: tview.AlignLeft})
-		table.SetCell(rowIndex, 1, &tview.TableCell{Text: fmt.Sprintf("%d", *participant.Series), Align: tview.AlignCenter})
-		if participant.Lane != nil {
-			table.SetCell(rowIndex, 2, &tview.TableCell{Text: fmt.Sprintf("%d", *participant.Lane), Align: tview.AlignCenter})
+	headers = append(headers, "Time", "Gap", "Points", "Status")
+	for col, header := range headers {
+		table.SetCell(0, col, &tview.TableCell{Text: header, Align: tview.AlignCenter, Color: tcell.ColorYellow})
+	}
+	timeIndex := 4 + laps
+
+	rowIndex := 1
+	for _, classification := range app.classifications {
+		if len(app.classifications) > 1 {
+			title := fmt.Sprintf("%s %s", classification.Gender, classification.Category)
+			table.SetCell(rowIndex, 1, &tview.TableCell{Text: title, Align: tview.AlignLeft, Color: tcell.ColorGreen})
+			rowIndex++
 		}
-		for j, lap := range *participant.Laps {
-			colIndex := 3 + j
-			table.SetCell(rowIndex, colIndex, &tview.TableCell{Text: fmt.Sprintf("%v", lap), Align: tview.AlignLeft})
+
+		for _, entry := range classification.Entries {
+			participant := entry.Participant
+			color := tcell.ColorWhite
+			if entry.Status == types.STATUS_DISQUALIFIED {
+				color = tcell.ColorRed
+			} else if entry.Status != types.STATUS_CLASSIFIED {
+				color = tcell.ColorGray
+			} else if participant.IsPenalized() {
+				color = tcell.ColorOrange
+			}
+
+			if entry.Position > 0 {
+				table.SetCell(rowIndex, 0, &tview.TableCell{Text: fmt.Sprintf("%d", entry.Position), Align: tview.AlignCenter, Color: color})
+			}
+			table.SetCell(rowIndex, 1, &tview.TableCell{Text: participant.Club.Name, Align: tview.AlignLeft, Color: color})
+			if participant.Series != nil {
+				table.SetCell(rowIndex, 2, &tview.TableCell{Text: fmt.Sprintf("%d", *participant.Series), Align: tview.AlignCenter})
+			}
+			if participant.Lane != nil {
+				table.SetCell(rowIndex, 3, &tview.TableCell{Text: fmt.Sprintf("%d", *participant.Lane), Align: tview.AlignCenter})
+			}
+			for j, lap := range participant.Laps {
+				if j >= laps {
+					break
+				}
+				table.SetCell(rowIndex, 4+j, &tview.TableCell{Text: types.FormatLapTime(lap), Align: tview.AlignLeft})
+			}
+			if entry.Status == types.STATUS_CLASSIFIED || entry.Status == types.STATUS_GUEST {
+				table.SetCell(rowIndex, timeIndex, &tview.TableCell{Text: types.FormatLapTime(entry.Time), Align: tview.AlignLeft, Color: color})
+			}
+			if entry.Position > 1 {
+				gap := fmt.Sprintf("+%s (%.2f%%)", types.FormatLapTime(entry.Gap), entry.GapPercentage)
+				table.SetCell(rowIndex, timeIndex+1, &tview.TableCell{Text: gap, Align: tview.AlignLeft})
+			}
+			if entry.Points > 0 {
+				table.SetCell(rowIndex, timeIndex+2, &tview.TableCell{Text: fmt.Sprintf("%g", entry.Points), Align: tview.AlignCenter})
+			}
+			table.SetCell(rowIndex, timeIndex+3, &tview.TableCell{Text: statusText(&entry), Align: tview.AlignLeft, Color: color})
+			rowIndex++
 		}
 	}
 
 	return table
 }
+
+// statusText shows the status of non classified crews followed by their penalties.
+func statusText(entry *types.ClassificationEntry) string {
+	parts := make([]string, 0, 2)
+	if entry.Status != types.STATUS_CLASSIFIED {
+		parts = append(parts, entry.Status)
+	}
+	if entry.Participant.IsPenalized() {
+		parts = append(parts, penaltyText(entry.Participant))
+	}
+	return strings.Join(parts, " ")
+}
+
+// penaltyText summarises the penalties of a participant: the added time and the reasons.
+func penaltyText(participant *types.Participant) string {
+	parts := make([]string, 0)
+	if penaltyTime := participant.PenaltyTime(); penaltyTime > 0 {
+		parts = append(parts, fmt.Sprintf("+%s", penaltyTime))
+	}
+	for _, penalty := range participant.Penalties {
+		if penalty.Reason != nil && *penalty.Reason != "" {
+			parts = append(parts, *penalty.Reason)
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -9,7 +9,7 @@ func (app *Application) bottomLegend() *tview.TextView {
 	legend := tview.NewTextView().
 		SetTextColor(tcell.ColorGreen).
 		SetTextAlign(tview.AlignCenter).
-		SetText("Press ESC to quit")
+		SetText("Press ESC to cancel a search or quit")
 
 	legend.Box.SetBorder(true)
 
@@ -1,12 +1,21 @@
 package tui
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/iagocanalejas/rstats/internal/service"
+	"github.com/iagocanalejas/rstats/internal/types"
 	"github.com/rivo/tview"
 )
 
+const (
+	pageSize          = 50 // races loaded on each page
+	loadMoreThreshold = 10 // remaining races in the list that trigger the load of the next page
+)
+
 func (app *Application) listView() *tview.List {
 	app.racesList = tview.NewList()
 	app.racesList.Box.SetBorder(true)
@@ -18,27 +27,98 @@ func (app *Application) listView() *tview.List {
 			return nil
 		case tcell.KeyEnter:
 			selectedItem := app.racesList.GetCurrentItem()
-			app.showDetailsView(app.races[selectedItem].ID)
+			if selectedItem < len(app.races) {
+				app.showDetailsView(app.races[selectedItem].ID)
+			}
 		}
 		return event
 	})
 
+	app.racesList.SetChangedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
+		if index >= len(app.races)-loadMoreThreshold {
+			app.loadMore()
+		}
+	})
+
 	app.populateList()
 
 	return app.racesList
 }
 
+// populateList starts a new search with the current keywords, cancelling any search still in flight. Only the first
+// page is loaded, the following ones are loaded as the user scrolls down the list.
 func (app *Application) populateList() {
+	if app.searching {
+		app.cancelSearch()
+	}
+
+	app.searchID++
+	app.listSearch = app.currentSearch
+	app.races = nil
+	app.nextPage = nil
 	app.racesList.Clear()
 
-	races, err := app.service.SearchRaces(app.currentSearch)
-	if err != nil {
-		app.errorModal(err)
+	app.fetchPage(nil)
+}
+
+// loadMore loads the next page of the current search if there is one and no other page is being loaded.
+func (app *Application) loadMore() {
+	if app.searching || app.nextPage == nil {
 		return
 	}
+	app.fetchPage(app.nextPage)
+}
+
+// fetchPage runs the search in the background and appends the results to the list from the UI goroutine.
+func (app *Application) fetchPage(after *service.RaceCursor) {
+	ctx, cancel := context.WithCancel(app.ctx)
+	app.cancelSearch = cancel
+	app.searching = true
+
+	searchID, keywords := app.searchID, app.listSearch
+	go func() {
+		defer cancel()
+		page, err := app.service.SearchRaces(ctx, keywords, after, pageSize)
+		cancelled := ctx.Err() != nil
+
+		app.App.QueueUpdateDraw(func() {
+			if searchID != app.searchID {
+				// a newer search replaced this one
+				return
+			}
+			app.searching = false
+
+			if cancelled {
+				return
+			}
+			if err != nil {
+				app.errorModal(err)
+				return
+			}
+
+			app.races = append(app.races, page.Races...)
+			app.nextPage = page.Next
+			for _, race := range page.Races {
+				app.racesList.AddItem(fmt.Sprintf("%d (%s)", race.ID, race.Date), highlightMatch(&race), 0, nil)
+			}
+		})
+	}()
+}
+
+// highlightMatch returns the race name with the value matched by a ranked search highlighted. Matches that are not
+// part of the name (leagues and clubs) are appended to it.
+func highlightMatch(race *types.Race) string {
+	name := tview.Escape(race.Name)
+	if race.Match == nil {
+		return name
+	}
 
-	app.races = races
-	for _, race := range races {
-		app.racesList.AddItem(fmt.Sprintf("%d (%s)", race.ID, race.Date), race.Name, 0, nil)
+	value := tview.Escape(race.Match.Value)
+	upperName, upperValue := strings.ToUpper(name), strings.ToUpper(value)
+	// upper-casing may change the byte length of some runes, indexes are only valid when it does not
+	if idx := strings.Index(upperName, upperValue); idx >= 0 && len(upperName) == len(name) {
+		end := idx + len(upperValue)
+		return fmt.Sprintf("%s[yellow]%s[-]%s", name[:idx], name[idx:end], name[end:])
 	}
+	return fmt.Sprintf("%s [yellow](%s: %s)[-]", name, race.Match.Field, value)
 }
@@ -19,7 +19,7 @@ func (app *Application) searchView() *tview.Flex {
 	legend := tview.NewTextView().
 		SetTextColor(tcell.ColorGreen).
 		SetTextAlign(tview.AlignLeft).
-		SetText("Filters -> year | trophy[_id] | flag[_id] | league[_id] | participant[_id]")
+		SetText("Filters -> year | trophy[_id] | flag[_id] | league[_id] | participant[_id] | mode:ranked|plain")
 
 	searchBox := tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(app.searchInput, 1, 0, true).
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/iagocanalejas/rstats/internal/types"
+	"github.com/rivo/tview"
+)
+
+// showStandingsView shows the standings of the season of the race in details, for the gender and category of its
+// first classification.
+func (app *Application) showStandingsView() {
+	if app.race == nil || app.race.League == nil || len(app.classifications) == 0 {
+		return
+	}
+
+	date, err := time.Parse("02-01-2006", app.race.Date)
+	if err != nil {
+		app.errorModal(err)
+		return
+	}
+
+	classification := app.classifications[0]
+	standings, err := app.service.GetLeagueStandings(
+		app.ctx, app.race.League.ID, date.Year(), classification.Gender, classification.Category,
+	)
+	if err != nil {
+		app.errorModal(err)
+		return
+	}
+
+	header := tview.NewTextView().
+		SetTextColor(tcell.ColorGreen).
+		SetTextAlign(tview.AlignLeft).
+		SetText(fmt.Sprintf("%s %d || %s %s", standings.League.Name, standings.Year, standings.Gender, standings.Category))
+	header.Box.SetBorder(true)
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(header, 3, 0, false).
+		AddItem(app.standingsTable(standings), 0, 1, true)
+
+	app.setRoot(flex)
+	app.showingStandings = true
+}
+
+func (app *Application) standingsTable(standings *types.Standings) *tview.Table {
+	table := tview.NewTable().
+		SetBorders(true).
+		SetFixed(1, 2)
+
+	headers := []string{"Pos", "Club Name", "Points"}
+	for round := range standings.Rounds {
+		headers = append(headers, fmt.Sprintf("R%d", round+1))
+	}
+	for col, header := range headers {
+		table.SetCell(0, col, &tview.TableCell{Text: header, Align: tview.AlignCenter, Color: tcell.ColorYellow})
+	}
+
+	for idx, entry := range standings.Entries {
+		row := idx + 1
+		table.SetCell(row, 0, &tview.TableCell{Text: fmt.Sprintf("%d", entry.Position), Align: tview.AlignCenter})
+		table.SetCell(row, 1, &tview.TableCell{Text: entry.Name(), Align: tview.AlignLeft})
+		table.SetCell(row, 2, &tview.TableCell{Text: fmt.Sprintf("%g", entry.Points), Align: tview.AlignCenter, Color: tcell.ColorGreen})
+		for round, result := range entry.Results {
+			if result.Position == 0 {
+				continue
+			}
+			color := tcell.ColorWhite
+			if result.Discarded {
+				color = tcell.ColorGray
+			}
+			text := fmt.Sprintf("%g (%d)", result.Points, result.Position)
+			table.SetCell(row, 3+round, &tview.TableCell{Text: text, Align: tview.AlignCenter, Color: color})
+		}
+	}
+
+	return table
+}